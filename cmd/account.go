@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	tmfy "github.com/hrmsk66/terraformify/lib"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// accountCmd represents the account command
+var accountCmd = &cobra.Command{
+	Use:          "account",
+	Short:        "Generate TF files for account-level resources such as users and service authorizations",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filter := tmfy.CreateLogFilter()
+		log.SetOutput(filter)
+		log.Printf("[INFO] CLI version: %s", version)
+
+		workingDir, err := cmd.Flags().GetString("working-dir")
+		if err != nil {
+			return err
+		}
+
+		apiKey := viper.GetString("api-key")
+		err = os.Setenv("FASTLY_API_KEY", apiKey)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		interactive, err := cmd.Flags().GetBool("interactive")
+		if err != nil {
+			return err
+		}
+		parallelism, err := cmd.Flags().GetInt("parallelism")
+		if err != nil {
+			return err
+		}
+		if parallelism < 1 {
+			return fmt.Errorf("--parallelism must be at least 1, got %d", parallelism)
+		}
+		nameTemplate, err := cmd.Flags().GetString("name-template")
+		if err != nil {
+			return err
+		}
+		c := tmfy.Config{
+			Directory:    workingDir,
+			Interactive:  interactive,
+			Parallelism:  parallelism,
+			NameTemplate: nameTemplate,
+		}
+
+		return importAccount(c)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(accountCmd)
+
+	// Persistent flags
+	accountCmd.PersistentFlags().IntP("parallelism", "p", 1, "Number of resources to import concurrently")
+	accountCmd.PersistentFlags().String("name-template", tmfy.DefaultNameTemplate, "Template for resource names (e.g. {{.Type}}_{{.Name}})")
+}
+
+func importAccount(c tmfy.Config) error {
+	path := filepath.Join(c.Directory, "account.tf")
+	if _, err := os.Stat(path); err == nil {
+		return errors.New("account.tf already exists in the working directory")
+	}
+
+	namer, err := tmfy.NewNamer(c.NameTemplate)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[INFO] Initializing Terraform")
+	// Find/Install Terraform binary
+	tf, err := tmfy.TerraformInstall(c.Directory)
+	if err != nil {
+		return err
+	}
+
+	// Create provider.tf unless the directory already has services terraformified
+	if _, err := os.Stat(filepath.Join(c.Directory, "provider.tf")); errors.Is(err, os.ErrNotExist) {
+		log.Printf("[INFO] Creating provider.tf")
+		if err := tmfy.CreateProviderFile(c); err != nil {
+			return err
+		}
+	}
+
+	// Run "terraform init"
+	log.Printf(`[INFO] Running "terraform init"`)
+	err = tmfy.TerraformInit(tf, c)
+	if err != nil {
+		return err
+	}
+
+	// Run "terraform version"
+	err = tmfy.TerraformVersion(tf)
+	if err != nil {
+		return err
+	}
+
+	log.Print("[INFO] Looking up users, service authorizations and custom dashboards")
+	client := tmfy.NewFastlyClient(os.Getenv("FASTLY_API_KEY"))
+	props, err := client.DiscoverAccountResources()
+	if err != nil {
+		return err
+	}
+	if err := namer.Assign(props...); err != nil {
+		return err
+	}
+
+	targets := make([]tmfy.TFBlockProp, 0, len(props))
+	for _, prop := range props {
+		// Ask yes/no if in interactive mode
+		if c.Interactive {
+			yes := tmfy.YesNo(fmt.Sprintf("import %s? ", prop.GetRef()))
+			if !yes {
+				continue
+			}
+		}
+		targets = append(targets, prop)
+	}
+	if len(targets) == 0 {
+		log.Print("[INFO] No resources to import")
+		return nil
+	}
+
+	// Create temp*.tf with empty resource blocks
+	tempf, err := tmfy.CreateTempFile(c)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tempf.Name())
+
+	// The parallel import merges into the existing state file,
+	// so the first resource is always imported on its own.
+	log.Printf(`[INFO] Running "terraform import" on %s`, targets[0].GetRef())
+	err = tmfy.TerraformImport(tf, targets[0], tempf)
+	if err != nil {
+		return err
+	}
+	if c.Parallelism > 1 {
+		log.Printf(`[INFO] Running "terraform import" on %d resources with parallelism %d`, len(targets)-1, c.Parallelism)
+		err = tmfy.TerraformImportParallel(tf, targets[1:], tempf, c.Parallelism)
+		if err != nil {
+			return err
+		}
+	} else {
+		for _, prop := range targets[1:] {
+			log.Printf(`[INFO] Running "terraform import" on %s`, prop.GetRef())
+			err = tmfy.TerraformImport(tf, prop, tempf)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// temp*.tf no longer needed
+	if err := tempf.Close(); err != nil {
+		return err
+	}
+	if err := os.Remove(tempf.Name()); err != nil {
+		return err
+	}
+
+	log.Print(`[INFO] Running "terraform show" to get the current Terraform state in HCL format`)
+	rawHCL, err := tmfy.TerraformShow(tf)
+	if err != nil {
+		return err
+	}
+
+	log.Print("[INFO] Parsing the HCL and making corrections")
+	tfconf, err := tmfy.LoadTFConf(rawHCL)
+	if err != nil {
+		return err
+	}
+	curState, err := tmfy.PullTFState(tf)
+	if err != nil {
+		return err
+	}
+	result, err := tfconf.RewriteAccountResources(curState)
+	if err != nil {
+		return err
+	}
+
+	log.Print("[INFO] Writing the configuration to account.tf")
+	if err := os.WriteFile(path, result, 0644); err != nil {
+		return err
+	}
+
+	log.Print(`[INFO] Running "terraform refresh" to format the state file and check errors`)
+	err = tmfy.TerraformRefresh(tf)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, tmfy.BoldGreen("Completed!"))
+	return nil
+}
@@ -0,0 +1,8 @@
+package cmd
+
+// aclCmd represents the acl command
+var aclCmd = newSingleResourceCmd(aclResource)
+
+func init() {
+	rootCmd.AddCommand(aclCmd)
+}
@@ -0,0 +1,8 @@
+package cmd
+
+// dictionaryCmd represents the dictionary command
+var dictionaryCmd = newSingleResourceCmd(dictionaryResource)
+
+func init() {
+	rootCmd.AddCommand(dictionaryCmd)
+}
@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	tmfy "github.com/hrmsk66/terraformify/lib"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff <service-a>[@version] <service-b>[@version]",
+	Short: "Show the differences between two services or two versions of a service",
+	Long: `Show the differences between two services or two versions of a service.
+Exits with 0 if they are the same, 1 if they differ, and 2 on errors.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if err := cobra.ExactArgs(2)(cmd, args); err != nil {
+			return &exitCodeError{code: 2, err: err}
+		}
+		return nil
+	},
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filter := tmfy.CreateLogFilter()
+		log.SetOutput(filter)
+		log.Printf("[INFO] CLI version: %s", version)
+
+		apiKey := viper.GetString("api-key")
+		err := os.Setenv("FASTLY_API_KEY", apiKey)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		a, err := parseServiceRef(args[0])
+		if err != nil {
+			return &exitCodeError{code: 2, err: err}
+		}
+		b, err := parseServiceRef(args[1])
+		if err != nil {
+			return &exitCodeError{code: 2, err: err}
+		}
+
+		differ, err := diffServices(os.Stdout, a, b)
+		if err != nil {
+			return &exitCodeError{code: 2, err: err}
+		}
+		if differ {
+			return &exitCodeError{code: 1}
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+}
+
+// parseServiceRef parses <service-id>[@version] into the config to import the service
+func parseServiceRef(ref string) (tmfy.Config, error) {
+	c := tmfy.Config{
+		ID:           ref,
+		Parallelism:  1,
+		NameTemplate: tmfy.DefaultNameTemplate,
+		// Both sides share the resource name so that the addresses match
+		ResourceName: "service",
+	}
+	if i := strings.LastIndex(ref, "@"); i >= 0 {
+		v, err := strconv.Atoi(ref[i+1:])
+		if err != nil || v < 1 {
+			return c, fmt.Errorf("invalid version in %q", ref)
+		}
+		c.ID = ref[:i]
+		c.Version = v
+	}
+	if c.ID == "" {
+		return c, fmt.Errorf("invalid service in %q", ref)
+	}
+	return c, nil
+}
+
+// diffServices imports the services into scratch directories and writes the differences to w.
+// It reports whether the services differ.
+func diffServices(w io.Writer, a, b tmfy.Config) (bool, error) {
+	scratch, err := os.MkdirTemp("", "terraformify-diff")
+	if err != nil {
+		return false, err
+	}
+	defer os.RemoveAll(scratch)
+
+	cleanup, err := sharePluginCache(filepath.Join(scratch, ".plugins"))
+	if err != nil {
+		return false, err
+	}
+	defer cleanup()
+
+	a.Directory = filepath.Join(scratch, "a")
+	b.Directory = filepath.Join(scratch, "b")
+	for _, c := range []*tmfy.Config{&a, &b} {
+		if err := os.Mkdir(c.Directory, 0755); err != nil {
+			return false, err
+		}
+		log.Printf("[INFO] Importing %s into a scratch directory", serviceRefString(*c))
+		if err := importService(*c); err != nil {
+			return false, fmt.Errorf("%s: %w", serviceRefString(*c), err)
+		}
+	}
+
+	aConf, err := loadMainTF(a.Directory)
+	if err != nil {
+		return false, err
+	}
+	bConf, err := loadMainTF(b.Directory)
+	if err != nil {
+		return false, err
+	}
+	blockChanges := tmfy.DiffBlocks(aConf, bConf)
+	fileChanges, err := tmfy.DiffFiles(a.Directory, b.Directory)
+	if err != nil {
+		return false, err
+	}
+	if len(blockChanges) == 0 && len(fileChanges) == 0 {
+		return false, nil
+	}
+
+	fmt.Fprintf(w, "--- a: %s\n+++ b: %s\n", serviceRefString(a), serviceRefString(b))
+	for _, c := range blockChanges {
+		fmt.Fprintf(w, "\n%s\n", tmfy.Bold(c.String()))
+		switch c.Kind {
+		case tmfy.ChangeAdded:
+			writePrefixedBlock(w, "+", c.To)
+		case tmfy.ChangeRemoved:
+			writePrefixedBlock(w, "-", c.From)
+		case tmfy.ChangeModified:
+			for _, attr := range c.Attributes {
+				if attr.From != "" {
+					fmt.Fprintf(w, "  - %s = %s\n", attr.Name, indentContinuation(attr.From))
+				}
+				if attr.To != "" {
+					fmt.Fprintf(w, "  + %s = %s\n", attr.Name, indentContinuation(attr.To))
+				}
+			}
+		}
+	}
+
+	for _, c := range fileChanges {
+		fmt.Fprintf(w, "\n%s\n", tmfy.Bold(c.String()))
+		var from, to []byte
+		if c.Kind != tmfy.ChangeAdded {
+			from, err = os.ReadFile(filepath.Join(a.Directory, c.Path))
+			if err != nil {
+				return false, err
+			}
+		}
+		if c.Kind != tmfy.ChangeRemoved {
+			to, err = os.ReadFile(filepath.Join(b.Directory, c.Path))
+			if err != nil {
+				return false, err
+			}
+		}
+		w.Write(tmfy.UnifiedDiff("a/"+c.Path, "b/"+c.Path, from, to))
+	}
+	return true, nil
+}
+
+// writePrefixedBlock writes the block with each line prefixed, like the lines of a unified diff
+func writePrefixedBlock(w io.Writer, prefix string, block *hclwrite.Block) {
+	f := hclwrite.NewEmptyFile()
+	f.Body().AppendBlock(block)
+	lines := strings.Split(strings.TrimRight(string(hclwrite.Format(f.Bytes())), "\n"), "\n")
+	for _, line := range lines {
+		fmt.Fprintf(w, "  %s %s\n", prefix, line)
+	}
+}
+
+// indentContinuation indents the lines of multi-line expressions under the attribute
+func indentContinuation(expr string) string {
+	return strings.ReplaceAll(expr, "\n", "\n    ")
+}
+
+func serviceRefString(c tmfy.Config) string {
+	if c.Version != 0 {
+		return fmt.Sprintf("%s@%d", c.ID, c.Version)
+	}
+	return c.ID
+}
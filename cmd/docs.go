@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	tmfy "github.com/hrmsk66/terraformify/lib"
+	"github.com/spf13/cobra"
+)
+
+// docsCmd represents the docs command
+var docsCmd = &cobra.Command{
+	Use:          "docs",
+	Short:        "Render a documentation page for the services in the working directory",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filter := tmfy.CreateLogFilter()
+		log.SetOutput(filter)
+
+		workingDir, err := cmd.Flags().GetString("working-dir")
+		if err != nil {
+			return err
+		}
+		format, err := cmd.Flags().GetString("format")
+		if err != nil {
+			return err
+		}
+		if err := tmfy.ValidateDocsFormat(format); err != nil {
+			return err
+		}
+		output, err := cmd.Flags().GetString("output")
+		if err != nil {
+			return err
+		}
+
+		b, err := os.ReadFile(filepath.Join(workingDir, "main.tf"))
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("main.tf is not found in %s. Run the service command first", workingDir)
+		} else if err != nil {
+			return err
+		}
+		tfconf, err := tmfy.LoadTFConf(string(b))
+		if err != nil {
+			return err
+		}
+		docs, err := tfconf.BuildServiceDocs(workingDir)
+		if err != nil {
+			return err
+		}
+		if len(docs) == 0 {
+			return errors.New("no services are found in main.tf")
+		}
+		page, err := tmfy.RenderServiceDocs(docs, format)
+		if err != nil {
+			return err
+		}
+
+		if output == "" {
+			_, err = os.Stdout.Write(page)
+			return err
+		}
+		// Links to the extracted files are relative to the working directory
+		if !filepath.IsAbs(output) {
+			output = filepath.Join(workingDir, output)
+		}
+		log.Printf("[INFO] Writing the docs to %s", output)
+		return os.WriteFile(output, page, 0644)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(docsCmd)
+
+	// Persistent flags
+	docsCmd.PersistentFlags().StringP("format", "f", "markdown", "Output format: markdown or html")
+	docsCmd.PersistentFlags().StringP("output", "o", "", "File in the working directory to write the docs to (defaults to stdout)")
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/hashicorp/terraform-exec/tfexec"
+	tmfy "github.com/hrmsk66/terraformify/lib"
+	"github.com/spf13/cobra"
+)
+
+// doctorCmd represents the doctor command
+var doctorCmd = &cobra.Command{
+	Use:   "doctor [dir]",
+	Short: "Find and fix the inconsistencies between the configuration and the state left by older versions",
+	Long: `Find and fix the inconsistencies between the configuration and the state left by older versions.
+The directory defaults to the working directory. With --fix, the state is backed up and then fixed.
+Exits with 0 if nothing is found or everything is fixed, 1 if issues are found, and 2 on errors.`,
+	Args:          cobra.MaximumNArgs(1),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filter := tmfy.CreateLogFilter()
+		log.SetOutput(filter)
+
+		dir, err := cmd.Flags().GetString("working-dir")
+		if err != nil {
+			return &exitCodeError{code: 2, err: err}
+		}
+		if len(args) == 1 {
+			dir = args[0]
+		}
+		fix, err := cmd.Flags().GetBool("fix")
+		if err != nil {
+			return &exitCodeError{code: 2, err: err}
+		}
+
+		if err := runDoctor(dir, fix); err != nil {
+			var exitErr *exitCodeError
+			if errors.As(err, &exitErr) {
+				return err
+			}
+			return &exitCodeError{code: 2, err: err}
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+
+	// Persistent flags
+	doctorCmd.PersistentFlags().Bool("fix", false, "Back up the state and fix the issues")
+}
+
+func runDoctor(dir string, fix bool) error {
+	// The state is pulled from the backend unless terraform.tfstate is in the directory
+	var tf *tfexec.Terraform
+	tfstate, err := tmfy.LoadTFState(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		tf, err = tmfy.TerraformInstall(dir)
+		if err != nil {
+			return err
+		}
+		log.Printf(`[INFO] Running "terraform state pull" in %s`, dir)
+		tfstate, err = tmfy.PullTFState(tf)
+	}
+	if err != nil {
+		return err
+	}
+
+	issues, err := tmfy.DiagnoseState(dir, tfstate)
+	if err != nil {
+		return err
+	}
+	for _, issue := range issues {
+		fmt.Println(issue)
+	}
+	if len(issues) == 0 {
+		log.Printf("[INFO] No issues are found in %s", dir)
+		return nil
+	}
+	if !fix {
+		log.Printf("[INFO] %d issues are found. Run with --fix to fix them", len(issues))
+		return &exitCodeError{code: 1}
+	}
+
+	newState, err := tfstate.RepairState(issues)
+	if err != nil {
+		return err
+	}
+	backup, err := tmfy.BackupTFState(dir, tfstate)
+	if err != nil {
+		return err
+	}
+	log.Printf("[INFO] Backed up the state to %s", backup)
+
+	if tf != nil {
+		log.Print(`[INFO] Running "terraform state push" to write the state`)
+		err = tmfy.PushTFState(tf, newState)
+	} else {
+		err = newState.Save(dir)
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("[INFO] Fixed %d issues", len(issues))
+	return nil
+}
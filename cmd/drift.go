@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/terraform-exec/tfexec"
+	tmfy "github.com/hrmsk66/terraformify/lib"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// driftCmd represents the drift command
+var driftCmd = &cobra.Command{
+	Use:   "drift",
+	Short: "Report changes made outside of Terraform to the resources in the working directory",
+	Long: `Report changes made outside of Terraform to the resources in the working directory.
+Exits with 0 if no drift is detected, 1 if drift is detected, and 2 on errors.`,
+	Args:          cobra.NoArgs,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filter := tmfy.CreateLogFilter()
+		log.SetOutput(filter)
+		log.Printf("[INFO] CLI version: %s", version)
+
+		workingDir, err := cmd.Flags().GetString("working-dir")
+		if err != nil {
+			return &exitCodeError{code: 2, err: err}
+		}
+
+		apiKey := viper.GetString("api-key")
+		err = os.Setenv("FASTLY_API_KEY", apiKey)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		format, err := cmd.Flags().GetString("format")
+		if err != nil {
+			return &exitCodeError{code: 2, err: err}
+		}
+		if err := tmfy.ValidateDriftReportFormat(format); err != nil {
+			return &exitCodeError{code: 2, err: err}
+		}
+		output, err := cmd.Flags().GetString("output")
+		if err != nil {
+			return &exitCodeError{code: 2, err: err}
+		}
+		watch, err := cmd.Flags().GetDuration("watch")
+		if err != nil {
+			return &exitCodeError{code: 2, err: err}
+		}
+		if watch != 0 && output == "" {
+			return &exitCodeError{code: 2, err: fmt.Errorf("--watch requires --output")}
+		}
+
+		tf, err := tmfy.TerraformInstall(workingDir)
+		if err != nil {
+			return &exitCodeError{code: 2, err: err}
+		}
+		log.Printf(`[INFO] Running "terraform init"`)
+		// The directory is an existing root module
+		err = tmfy.TerraformInit(tf, tmfy.Config{Directory: workingDir, Into: true})
+		if err != nil {
+			return &exitCodeError{code: 2, err: err}
+		}
+
+		if watch != 0 {
+			return watchDrift(tf, format, output, watch)
+		}
+
+		report, err := checkDrift(tf, format, output)
+		if err != nil {
+			return &exitCodeError{code: 2, err: err}
+		}
+		if len(report.Drifts) > 0 {
+			return &exitCodeError{code: 1}
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(driftCmd)
+
+	// Persistent flags
+	driftCmd.PersistentFlags().StringP("format", "f", "text", "Output format: text, json, junit or markdown")
+	driftCmd.PersistentFlags().StringP("output", "o", "", "File to write the report to (defaults to stdout)")
+	driftCmd.PersistentFlags().Duration("watch", 0, "Check drift repeatedly at the interval (e.g. 10m), writing the latest report to --output")
+}
+
+// checkDrift checks drift once and writes the report
+func checkDrift(tf *tfexec.Terraform, format, output string) (*tmfy.DriftReport, error) {
+	log.Print(`[INFO] Running "terraform plan -refresh-only" to detect drift`)
+	report, err := tmfy.CheckDrift(tf)
+	if err != nil {
+		return nil, err
+	}
+	b, err := report.Format(format)
+	if err != nil {
+		return nil, err
+	}
+
+	if output == "" {
+		_, err = os.Stdout.Write(b)
+		return report, err
+	}
+	log.Printf("[INFO] Writing the report to %s", output)
+	return report, writeFileAtomic(output, b)
+}
+
+// watchDrift checks drift at the interval until the process is stopped.
+// Failed checks are logged and retried at the next interval so that a transient error does not stop the watch.
+func watchDrift(tf *tfexec.Terraform, format, output string, interval time.Duration) error {
+	for {
+		report, err := checkDrift(tf, format, output)
+		if err != nil {
+			log.Printf("[ERROR] Drift check failed: %s", err)
+		} else if len(report.Drifts) > 0 {
+			log.Printf("[WARN] %d changes made outside of Terraform", len(report.Drifts))
+		}
+		time.Sleep(interval)
+	}
+}
+
+// writeFileAtomic replaces the file so that readers never see a partially written report
+func writeFileAtomic(path string, b []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(f.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	tmfy "github.com/hrmsk66/terraformify/lib"
+	"github.com/spf13/cobra"
+)
+
+// graphCmd represents the graph command
+var graphCmd = &cobra.Command{
+	Use:          "graph",
+	Short:        "Output the graph of the references between the blocks in the working directory",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filter := tmfy.CreateLogFilter()
+		log.SetOutput(filter)
+
+		workingDir, err := cmd.Flags().GetString("working-dir")
+		if err != nil {
+			return err
+		}
+		format, err := cmd.Flags().GetString("format")
+		if err != nil {
+			return err
+		}
+
+		b, err := os.ReadFile(filepath.Join(workingDir, "main.tf"))
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("main.tf is not found in %s. Run the service command first", workingDir)
+		} else if err != nil {
+			return err
+		}
+		tfconf, err := tmfy.LoadTFConf(string(b))
+		if err != nil {
+			return err
+		}
+		graph := tfconf.BuildGraph()
+
+		var out []byte
+		switch format {
+		case "dot":
+			out = graph.Dot()
+		case "mermaid":
+			out = graph.Mermaid()
+		default:
+			return fmt.Errorf("unknown format %q: must be one of dot, mermaid", format)
+		}
+
+		for _, issue := range graph.Issues() {
+			log.Printf("[WARN] %s", issue)
+		}
+		_, err = os.Stdout.Write(out)
+		return err
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(graphCmd)
+
+	// Persistent flags
+	graphCmd.PersistentFlags().StringP("format", "f", "dot", "Output format: dot or mermaid")
+}
@@ -0,0 +1,225 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	tmfy "github.com/hrmsk66/terraformify/lib"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:          "history <service-id>",
+	Short:        "Import the versions of an existing Fastly service and write a changelog",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filter := tmfy.CreateLogFilter()
+		log.SetOutput(filter)
+		log.Printf("[INFO] CLI version: %s", version)
+
+		workingDir, err := cmd.Flags().GetString("working-dir")
+		if err != nil {
+			return err
+		}
+
+		apiKey := viper.GetString("api-key")
+		err = os.Setenv("FASTLY_API_KEY", apiKey)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		from, err := cmd.Flags().GetInt("from")
+		if err != nil {
+			return err
+		}
+		to, err := cmd.Flags().GetInt("to")
+		if err != nil {
+			return err
+		}
+		if to == 0 {
+			client := tmfy.NewFastlyClient(apiKey)
+			to, err = client.GetLatestVersion(args[0])
+			if err != nil {
+				return err
+			}
+		}
+		if from < 1 || from > to {
+			return fmt.Errorf("invalid version range: --from %d --to %d", from, to)
+		}
+
+		err = importHistory(args[0], from, to, workingDir)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, tmfy.BoldGreen("Completed!"))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+
+	// Persistent flags
+	historyCmd.PersistentFlags().Int("from", 1, "First version of the service to be imported")
+	historyCmd.PersistentFlags().Int("to", 0, "Last version of the service to be imported (defaults to the latest version)")
+}
+
+// importedMarker is written to the directory of a version once the version has been imported
+const importedMarker = ".imported"
+
+func importHistory(serviceID string, from, to int, workingDir string) error {
+	versionsDir := filepath.Join(workingDir, "versions")
+	if err := os.MkdirAll(versionsDir, 0755); err != nil {
+		return err
+	}
+
+	// Share the provider plugins among the versions instead of downloading them for each version
+	cleanup, err := sharePluginCache(filepath.Join(versionsDir, ".plugins"))
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	for v := from; v <= to; v++ {
+		dir := filepath.Join(versionsDir, fmt.Sprintf("v%d", v))
+		// Versions imported by the previous run are kept so that the command can be resumed
+		if _, err := os.Stat(filepath.Join(dir, importedMarker)); err == nil {
+			log.Printf("[INFO] Version %d is already imported in %s. Skipping", v, dir)
+			continue
+		}
+		// The version the previous run failed to import is imported again from scratch
+		if err := os.RemoveAll(dir); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		if err := tmfy.CheckDirEmpty(dir); err != nil {
+			return err
+		}
+
+		log.Printf("[INFO] Importing version %d into %s", v, dir)
+		// The resource name is fixed so that renaming the service does not show up as a change
+		c := tmfy.Config{
+			ID:           serviceID,
+			Version:      v,
+			Directory:    dir,
+			Parallelism:  1,
+			NameTemplate: tmfy.DefaultNameTemplate,
+			ResourceName: "service",
+		}
+		if err := importService(c); err != nil {
+			return fmt.Errorf("version %d: %w", v, err)
+		}
+		if err := cleanupVersionDir(dir); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, importedMarker), nil, 0644); err != nil {
+			return err
+		}
+	}
+
+	log.Print("[INFO] Writing the changelog to versions/CHANGELOG.md")
+	changelog, err := buildChangelog(serviceID, from, to, versionsDir)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(versionsDir, "CHANGELOG.md"), changelog, 0644)
+}
+
+// cleanupVersionDir removes everything but main.tf and the extracted files
+func cleanupVersionDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.Name() == "main.tf" || (e.IsDir() && e.Name() != ".terraform") {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(dir, e.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func buildChangelog(serviceID string, from, to int, versionsDir string) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# Changelog of %s\n", serviceID)
+
+	var prevDir string
+	var prevConf *tmfy.TFConf
+	for v := from; v <= to; v++ {
+		dir := filepath.Join(versionsDir, fmt.Sprintf("v%d", v))
+		tfconf, err := loadMainTF(dir)
+		if err != nil {
+			return nil, err
+		}
+
+		fmt.Fprintf(&buf, "\n## v%d\n\n", v)
+		if prevConf == nil {
+			fmt.Fprintf(&buf, "Imported as the base of the changelog.\n")
+		} else {
+			blockChanges := tmfy.DiffBlocks(prevConf, tfconf)
+			fileChanges, err := tmfy.DiffFiles(prevDir, dir)
+			if err != nil {
+				return nil, err
+			}
+			if len(blockChanges) == 0 && len(fileChanges) == 0 {
+				fmt.Fprintf(&buf, "No changes from v%d.\n", v-1)
+			}
+			for _, c := range blockChanges {
+				fmt.Fprintf(&buf, "- `%s`\n", c)
+			}
+			for _, c := range fileChanges {
+				fmt.Fprintf(&buf, "- `%s`\n", c)
+			}
+		}
+
+		prevDir = dir
+		prevConf = tfconf
+	}
+	return buf.Bytes(), nil
+}
+
+func loadMainTF(dir string) (*tmfy.TFConf, error) {
+	b, err := os.ReadFile(filepath.Join(dir, "main.tf"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("main.tf is not found in %s", dir)
+	} else if err != nil {
+		return nil, err
+	}
+	return tmfy.LoadTFConf(string(b))
+}
+
+// sharePluginCache lets the following "terraform init" share the provider plugins through the cache directory,
+// unless TF_PLUGIN_CACHE_DIR is already set. The returned function removes the directory.
+func sharePluginCache(dir string) (func(), error) {
+	if os.Getenv("TF_PLUGIN_CACHE_DIR") != "" {
+		return func() {}, nil
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	if err := os.Setenv("TF_PLUGIN_CACHE_DIR", dir); err != nil {
+		return nil, err
+	}
+	return func() {
+		os.Unsetenv("TF_PLUGIN_CACHE_DIR")
+		os.RemoveAll(dir)
+	}, nil
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	tmfy "github.com/hrmsk66/terraformify/lib"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// lintCmd represents the lint command
+var lintCmd = &cobra.Command{
+	Use:   "lint",
+	Short: "Check the configuration in the working directory for common Fastly misconfigurations",
+	Long: `Check the configuration in the working directory for common Fastly misconfigurations.
+The level of each rule can be set to off, warning or error with --rule, or in the config file:
+
+  lint:
+    rules:
+      backend-without-healthcheck: off
+
+Exits with 0 if no rule at the error level is violated, 1 if any is, and 2 on errors.`,
+	Args:          cobra.NoArgs,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filter := tmfy.CreateLogFilter()
+		log.SetOutput(filter)
+
+		workingDir, err := cmd.Flags().GetString("working-dir")
+		if err != nil {
+			return &exitCodeError{code: 2, err: err}
+		}
+		format, err := cmd.Flags().GetString("format")
+		if err != nil {
+			return &exitCodeError{code: 2, err: err}
+		}
+		production, err := cmd.Flags().GetBool("production")
+		if err != nil {
+			return &exitCodeError{code: 2, err: err}
+		}
+		rules, err := cmd.Flags().GetStringToString("rule")
+		if err != nil {
+			return &exitCodeError{code: 2, err: err}
+		}
+		if format != "text" && format != "sarif" {
+			return &exitCodeError{code: 2, err: fmt.Errorf("unknown format %q: must be one of text, sarif", format)}
+		}
+
+		// Levels given by the flags take precedence over the config file
+		levels, err := tmfy.LintLevels(viper.GetStringMap("lint.rules"))
+		if err != nil {
+			return &exitCodeError{code: 2, err: err}
+		}
+		cfg := tmfy.LintConfig{
+			Levels:     levels,
+			Production: production || viper.GetBool("lint.production"),
+		}
+		for id, level := range rules {
+			cfg.Levels[id] = level
+		}
+		if err := tmfy.ValidateLintConfig(cfg); err != nil {
+			return &exitCodeError{code: 2, err: err}
+		}
+
+		if _, err := os.Stat(filepath.Join(workingDir, "main.tf")); errors.Is(err, os.ErrNotExist) {
+			return &exitCodeError{code: 2, err: fmt.Errorf("main.tf is not found in %s. Run the service command first", workingDir)}
+		}
+		findings, err := tmfy.Lint(workingDir, "main.tf", cfg)
+		if err != nil {
+			return &exitCodeError{code: 2, err: err}
+		}
+
+		switch format {
+		case "text":
+			for _, f := range findings {
+				fmt.Println(f)
+			}
+		case "sarif":
+			b, err := tmfy.LintSARIF(findings, version)
+			if err != nil {
+				return &exitCodeError{code: 2, err: err}
+			}
+			if _, err := os.Stdout.Write(b); err != nil {
+				return &exitCodeError{code: 2, err: err}
+			}
+		}
+
+		for _, f := range findings {
+			if f.Level == tmfy.LintLevelError {
+				return &exitCodeError{code: 1}
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(lintCmd)
+
+	// Persistent flags
+	lintCmd.PersistentFlags().StringP("format", "f", "text", "Output format: text or sarif")
+	lintCmd.PersistentFlags().Bool("production", false, "Enable the rules that only apply to production services")
+	lintCmd.PersistentFlags().StringToString("rule", nil, "Level of a rule: off, warning or error (e.g. --rule partial-shielding=error)")
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tmfy "github.com/hrmsk66/terraformify/lib"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// queryCmd represents the query command
+var queryCmd = &cobra.Command{
+	Use:   "query <service-id[@version]|dir> <jq-expression|shortcut>",
+	Short: "Run a jq expression against the state of a service or a directory",
+	Long: fmt.Sprintf(`Run a jq expression against the state of a service or a directory.
+If the first argument is an existing directory, its terraform.tfstate, or the state in its backend, is queried.
+Otherwise the service is imported into a scratch directory.
+The shortcuts are: %s`, strings.Join(tmfy.QueryShortcutNames(), ", ")),
+	Args:         cobra.ExactArgs(2),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filter := tmfy.CreateLogFilter()
+		log.SetOutput(filter)
+		log.Printf("[INFO] CLI version: %s", version)
+
+		format, err := cmd.Flags().GetString("format")
+		if err != nil {
+			return err
+		}
+		if err := tmfy.ValidateQueryFormat(format); err != nil {
+			return err
+		}
+
+		query := args[1]
+		var columns []string
+		if shortcut, ok := tmfy.QueryShortcuts[query]; ok {
+			log.Printf("[INFO] Using the %s shortcut: %s", query, shortcut.Description)
+			query = shortcut.Query
+			columns = shortcut.Columns
+		}
+
+		tfstate, err := loadQueryState(args[0])
+		if err != nil {
+			return err
+		}
+		results, err := tfstate.QueryAll(query)
+		if err != nil {
+			return err
+		}
+		out, err := tmfy.FormatQueryResults(results, format, columns)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(out)
+		return err
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(queryCmd)
+
+	// Persistent flags
+	queryCmd.PersistentFlags().StringP("format", "f", "json", "Output format: json, yaml or table")
+}
+
+// loadQueryState returns the state of the directory, or of the service imported into a scratch directory
+func loadQueryState(source string) (*tmfy.TFState, error) {
+	if fi, err := os.Stat(source); err == nil && fi.IsDir() {
+		return loadDirState(source)
+	}
+
+	c, err := parseServiceRef(source)
+	if err != nil {
+		return nil, err
+	}
+	apiKey := viper.GetString("api-key")
+	if err := os.Setenv("FASTLY_API_KEY", apiKey); err != nil {
+		return nil, err
+	}
+
+	scratch, err := os.MkdirTemp("", "terraformify-query")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(scratch)
+	c.Directory = scratch
+
+	log.Printf("[INFO] Importing %s into a scratch directory", serviceRefString(c))
+	if err := importService(c); err != nil {
+		return nil, err
+	}
+	return tmfy.LoadTFState(scratch)
+}
+
+// loadDirState reads terraform.tfstate of the directory, or pulls the state from its backend
+func loadDirState(dir string) (*tmfy.TFState, error) {
+	tfstate, err := tmfy.LoadTFState(dir)
+	if err == nil || !errors.Is(err, os.ErrNotExist) {
+		return tfstate, err
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".terraform")); err != nil {
+		return nil, fmt.Errorf("neither terraform.tfstate nor .terraform is found in %s", dir)
+	}
+
+	tf, err := tmfy.TerraformInstall(dir)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf(`[INFO] Running "terraform state pull" in %s`, dir)
+	return tmfy.PullTFState(tf)
+}
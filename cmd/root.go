@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"strings"
@@ -23,10 +24,35 @@ var rootCmd = &cobra.Command{
 func Execute() {
 	err := rootCmd.Execute()
 	if err != nil {
+		var exitErr *exitCodeError
+		if errors.As(err, &exitErr) {
+			if exitErr.err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", exitErr.err)
+			}
+			os.Exit(exitErr.code)
+		}
 		os.Exit(1)
 	}
 }
 
+// exitCodeError makes the command exit with the code.
+// Commands returning it set SilenceErrors, and the error, if any, is printed by Execute.
+type exitCodeError struct {
+	code int
+	err  error
+}
+
+func (e *exitCodeError) Error() string {
+	if e.err == nil {
+		return fmt.Sprintf("exit status %d", e.code)
+	}
+	return e.err.Error()
+}
+
+func (e *exitCodeError) Unwrap() error {
+	return e.err
+}
+
 func init() {
 	cobra.OnInitialize(initConfig)
 
@@ -1,12 +1,13 @@
 package cmd
 
 import (
-	"context"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/hashicorp/terraform-exec/tfexec"
 	tmfy "github.com/hrmsk66/terraformify/lib"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -27,10 +28,23 @@ var serviceCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		err = tmfy.CheckDirEmpty(workingDir)
+		into, err := cmd.Flags().GetString("into")
 		if err != nil {
 			return err
 		}
+		workspace, err := cmd.Flags().GetString("workspace")
+		if err != nil {
+			return err
+		}
+		if into != "" {
+			// The root module is not empty, and has the provider configured
+			workingDir = into
+		} else {
+			err = tmfy.CheckDirEmpty(workingDir)
+			if err != nil {
+				return err
+			}
+		}
 
 		apiKey := viper.GetString("api-key")
 		err = os.Setenv("FASTLY_API_KEY", apiKey)
@@ -50,15 +64,124 @@ var serviceCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
+		parallelism, err := cmd.Flags().GetInt("parallelism")
+		if err != nil {
+			return err
+		}
+		if parallelism < 1 {
+			return fmt.Errorf("--parallelism must be at least 1, got %d", parallelism)
+		}
+		movedFrom, err := cmd.Flags().GetString("moved-from")
+		if err != nil {
+			return err
+		}
+		nameTemplate, err := cmd.Flags().GetString("name-template")
+		if err != nil {
+			return err
+		}
+		resourceName, err := cmd.Flags().GetString("resource-name")
+		if err != nil {
+			return err
+		}
+		if resourceName != "" {
+			if err := tmfy.ValidateResourceName(resourceName); err != nil {
+				return err
+			}
+		}
+		extractThreshold, err := cmd.Flags().GetInt("extract-threshold")
+		if err != nil {
+			return err
+		}
+		withTLS, err := cmd.Flags().GetBool("with-tls")
+		if err != nil {
+			return err
+		}
+		mergeForEach, err := cmd.Flags().GetBool("merge-for-each")
+		if err != nil {
+			return err
+		}
+		logFormatTemplates, err := cmd.Flags().GetBool("log-format-templates")
+		if err != nil {
+			return err
+		}
+		dedupe, err := cmd.Flags().GetBool("dedupe")
+		if err != nil {
+			return err
+		}
+		backend, err := cmd.Flags().GetString("backend")
+		if err != nil {
+			return err
+		}
+		backendSettings, err := cmd.Flags().GetStringArray("backend-config")
+		if err != nil {
+			return err
+		}
+		if backend != "" && into != "" {
+			return fmt.Errorf("--backend cannot be used with --into. The backend of the root module is used")
+		}
+		if len(backendSettings) > 0 && backend == "" {
+			return fmt.Errorf("--backend-config requires --backend")
+		}
+		backendConfig, err := tmfy.ParseBackendConfig(backendSettings)
+		if err != nil {
+			return err
+		}
+		layoutSettings, err := cmd.Flags().GetStringArray("layout")
+		if err != nil {
+			return err
+		}
+		fileLayouts, err := loadFileLayouts(layoutSettings)
+		if err != nil {
+			return err
+		}
+		if dedupe && len(fileLayouts) > 0 {
+			log.Printf("[WARN] The layouts are not used for the content written to common/ with --dedupe")
+		}
+		if backend != "" && parallelism > 1 {
+			// Parallel imports write to per-resource local state files, which only the local backend supports
+			log.Printf("[WARN] --parallelism is not supported with --backend. Importing the resources sequentially")
+			parallelism = 1
+		}
+		if into != "" && parallelism > 1 {
+			configured, err := tmfy.ConfiguredBackend(into)
+			if err != nil {
+				return err
+			}
+			if configured != "" && configured != "local" {
+				log.Printf("[WARN] --parallelism is not supported with the %s backend of %s. Importing the resources sequentially", configured, into)
+				parallelism = 1
+			}
+		}
 		c := tmfy.Config{
-			ID:          args[0],
-			Version:     version,
-			Directory:   workingDir,
-			Interactive: interactive,
-			ManageAll:   manageAll,
+			ID:                 args[0],
+			Version:            version,
+			Directory:          workingDir,
+			Interactive:        interactive,
+			ManageAll:          manageAll,
+			Parallelism:        parallelism,
+			MovedFrom:          movedFrom,
+			NameTemplate:       nameTemplate,
+			ResourceName:       resourceName,
+			ExtractThreshold:   extractThreshold,
+			WithTLS:            withTLS,
+			Backend:            backend,
+			BackendConfig:      backendConfig,
+			Into:               into != "",
+			Workspace:          workspace,
+			MergeForEach:       mergeForEach,
+			LogFormatTemplates: logFormatTemplates,
+			Dedupe:             dedupe,
+			FileLayouts:        fileLayouts,
+		}
+
+		err = importService(c)
+		if err != nil {
+			return err
 		}
 
-		return importService(c)
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, tmfy.BoldGreen("Completed!"))
+		return nil
 	},
 }
 
@@ -68,9 +191,23 @@ func init() {
 	// Persistent flags
 	serviceCmd.PersistentFlags().IntP("version", "v", 0, "Version of the service to be imported")
 	serviceCmd.PersistentFlags().BoolP("manage-all", "m", false, "Manage all associated resources")
+	serviceCmd.PersistentFlags().IntP("parallelism", "p", 1, "Number of associated resources to import concurrently")
+	serviceCmd.PersistentFlags().String("moved-from", "", "Previously terraformified directory to generate moved blocks against")
+	serviceCmd.PersistentFlags().String("name-template", tmfy.DefaultNameTemplate, "Template for resource names (e.g. {{.Type}}_{{.Name}})")
+	serviceCmd.PersistentFlags().String("resource-name", "", "Resource name of the service (defaults to the service name)")
+	serviceCmd.PersistentFlags().Int("extract-threshold", 0, "Write dictionaries and ACLs with more items than this to data files (0 to disable)")
+	serviceCmd.PersistentFlags().Bool("with-tls", false, "Import TLS resources bound to the domains of the service")
+	serviceCmd.PersistentFlags().String("backend", "", "Type of the backend to write the state to (e.g. s3). Defaults to the local backend")
+	serviceCmd.PersistentFlags().StringArray("backend-config", nil, "Setting of the backend as key=value (e.g. bucket=tfstate). Can be repeated")
+	serviceCmd.PersistentFlags().String("into", "", "Existing root module to import the service into, writing the configuration to <service>.tf")
+	serviceCmd.PersistentFlags().String("workspace", "", "Workspace to select before importing")
+	serviceCmd.PersistentFlags().Bool("dedupe", false, "Write VCL, log formats and response contents to common/, one copy for the same content")
+	serviceCmd.PersistentFlags().Bool("log-format-templates", false, "Write the log formats that have the service name, ID or region as templates for templatefile()")
+	serviceCmd.PersistentFlags().StringArray("layout", nil, "Path template of the extracted files of a kind as kind=template (e.g. vcl=files/vcl/main/{{.Name}}.{{.Ext}}). Can be repeated")
+	serviceCmd.PersistentFlags().Bool("merge-for-each", false, "Generate one for_each resource per service for all ACLs, dictionaries and dynamic snippets")
 }
 
-func importService(c tmfy.Config) error {
+func importService(c tmfy.Config) (err error) {
 	log.Printf("[INFO] Initializing Terraform")
 	// Find/Install Terraform binary
 	tf, err := tmfy.TerraformInstall(c.Directory)
@@ -78,22 +215,36 @@ func importService(c tmfy.Config) error {
 		return err
 	}
 
-	// Create provider.tf
+	// Create provider.tf unless importing into an existing root module
 	// Create temp*.tf with empty service resource blocks
-	log.Printf("[INFO] Creating provider.tf and temp*.tf")
-	tempf, err := tmfy.CreateInitTerraformFiles(c)
-	defer os.Remove(tempf.Name())
+	var tempf *os.File
+	if c.Into {
+		log.Printf("[INFO] Creating temp*.tf")
+		tempf, err = tmfy.CreateTempFile(c)
+	} else {
+		log.Printf("[INFO] Creating provider.tf and temp*.tf")
+		tempf, err = tmfy.CreateInitTerraformFiles(c)
+	}
 	if err != nil {
 		return err
 	}
+	defer os.Remove(tempf.Name())
 
 	// Run "terraform init"
 	log.Printf(`[INFO] Running "terraform init"`)
-	err = tmfy.TerraformInit(tf)
+	err = tmfy.TerraformInit(tf, c)
 	if err != nil {
 		return err
 	}
 
+	if c.Workspace != "" {
+		log.Printf(`[INFO] Running "terraform workspace select %s"`, c.Workspace)
+		err = tmfy.TerraformWorkspaceSelect(tf, c.Workspace)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Run "terraform version"
 	err = tmfy.TerraformVersion(tf)
 	if err != nil {
@@ -101,7 +252,91 @@ func importService(c tmfy.Config) error {
 	}
 
 	// Create VCLServiceResourceProp struct
+	namer, err := tmfy.NewNamer(c.NameTemplate)
+	if err != nil {
+		return err
+	}
+	if c.Into {
+		// Keep the resources in the root module, including the placeholder name of the service, from colliding with the new ones
+		addrs, err := tmfy.ExistingResourceAddresses(tf)
+		if err != nil {
+			return err
+		}
+		log.Printf("[INFO] Found %d resources in the configuration and the state of %s", len(addrs), c.Directory)
+		for _, addr := range addrs {
+			if i := strings.Index(addr, "."); i > 0 {
+				namer.Reserve(addr[:i], addr[i+1:])
+			}
+		}
+		if c.ResourceName != "" && namer.Reserved("fastly_service_vcl", c.ResourceName) {
+			return fmt.Errorf("fastly_service_vcl.%s already exists in %s. Choose another name with --resource-name", c.ResourceName, c.Directory)
+		}
+	}
 	serviceProp := tmfy.NewVCLServiceResourceProp(c.ID, "service", c.Version)
+	if c.ResourceName != "" {
+		serviceProp.SetNormalizedName(c.ResourceName)
+	} else if c.Into {
+		// Resolve the final name before anything is imported into the state of the root module
+		client := tmfy.NewFastlyClient(os.Getenv("FASTLY_API_KEY"))
+		name, err := client.GetServiceName(c.ID)
+		if err != nil {
+			return err
+		}
+		serviceProp.Name = name
+		if err := namer.Assign(serviceProp); err != nil {
+			return err
+		}
+	} else if err := namer.Assign(serviceProp); err != nil {
+		return err
+	}
+
+	// The addresses in the state of the root module before the import
+	var before []string
+	if c.Into {
+		// Keep the files of the service apart from those of the other services in the root module
+		c.FileDir = serviceProp.GetNormalizedName()
+		names := []string{c.FileDir + ".tf", c.FileDir}
+		if c.WithTLS {
+			names = append(names, c.FileDir+"_tls.tf")
+		}
+		if c.MovedFrom != "" {
+			names = append(names, c.FileDir+"_moved.tf")
+		}
+		for _, name := range names {
+			if _, err := os.Stat(filepath.Join(c.Directory, name)); err == nil {
+				return fmt.Errorf("%s already exists in %s. Choose another name with --resource-name", name, c.Directory)
+			}
+		}
+		if c.MergeForEach {
+			if err := namer.ReserveMergedNames(serviceProp); err != nil {
+				return fmt.Errorf("%w in %s. Choose another name with --resource-name", err, c.Directory)
+			}
+		}
+
+		// Remove what has been imported if the import fails halfway
+		before, err = tmfy.StateResourceAddresses(tf)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err == nil {
+				return
+			}
+			// None of the files existed before the import
+			for _, name := range names {
+				os.RemoveAll(filepath.Join(c.Directory, name))
+			}
+			log.Printf("[INFO] Removing the imported resources from the state of %s", c.Directory)
+			removed, rmErr := tmfy.RemoveNewResources(tf, before)
+			if rmErr != nil {
+				log.Printf(`[ERROR] Failed to remove the imported resources: %v. Remove them with "terraform state rm"`, rmErr)
+				return
+			}
+			for _, addr := range removed {
+				log.Printf("[INFO] Removed %s", addr)
+			}
+		}()
+	}
 
 	// log.Printf(`[INFO] Running "terraform import %s %s"`, serviceProp.GetRef(), serviceProp.GetIDforTFImport())
 	log.Printf(`[INFO] Running "terraform import" on %s`, serviceProp.GetRef())
@@ -113,6 +348,9 @@ func importService(c tmfy.Config) error {
 	// Get the config represented in HCL from the "terraform show" output
 	log.Print(`[INFO] Running "terraform show" to get the current Terraform state in HCL format`)
 	rawHCL, err := tmfy.TerraformShow(tf)
+	if err != nil {
+		return err
+	}
 
 	// Parse HCL and obtain Terraform block props as a list of struct
 	// to get the overall picture of the service configuration
@@ -122,16 +360,68 @@ func importService(c tmfy.Config) error {
 	if err != nil {
 		return err
 	}
+	// The state has the other resources of the root module as well
+	tfconf = tfconf.SelectProps(serviceProp)
+
+	// Name the service resource after the service unless the name is given or already resolved
+	if c.ResourceName == "" && !c.Into {
+		name, err := tfconf.GetServiceName(serviceProp)
+		if err != nil {
+			return err
+		}
+
+		from := serviceProp.GetNormalizedName()
+		serviceProp.Name = name
+		if err := namer.Assign(serviceProp); err != nil {
+			return err
+		}
+
+		if from != serviceProp.GetNormalizedName() {
+			log.Printf(`[INFO] Renaming %s.%s to %s`, serviceProp.GetType(), from, serviceProp.GetRef())
+			err = tmfy.TerraformRename(tf, serviceProp, from, tempf)
+			if err != nil {
+				return err
+			}
+		}
+	}
 
 	props, err := tfconf.ParseVCLServiceResource(serviceProp, c)
 	if err != nil {
 		return err
 	}
 
-	// Iterate over the list of props and run terraform import for WAF, ACL/dicitonary items, and dynamic snippets
+	// Look up the stores linked to the service
+	links, err := tfconf.GetResourceLinks(serviceProp)
+	if err != nil {
+		return err
+	}
+	if len(links) > 0 {
+		log.Print("[INFO] Looking up the stores linked to the service")
+		client := tmfy.NewFastlyClient(os.Getenv("FASTLY_API_KEY"))
+		stores, err := client.DiscoverLinkedStores(links, serviceProp)
+		if err != nil {
+			return err
+		}
+		props = append(props, stores...)
+	}
+
+	// Assign valid and unique resource names to the associated resources
+	if c.MergeForEach && !c.Into {
+		// The name of the service is final by now. With --into, it has been checked before the import.
+		if err := namer.ReserveMergedNames(serviceProp); err != nil {
+			return err
+		}
+	}
+	if err := namer.Assign(props...); err != nil {
+		return err
+	}
+
+	// Select WAF, ACL/dicitonary items, dynamic snippets and stores to be imported
+	targets := make([]tmfy.TFBlockProp, 0, len(props))
 	for _, prop := range props {
 		switch r := prop.(type) {
-		case *tmfy.WAFResourceProp, *tmfy.ACLResourceProp, *tmfy.DictionaryResourceProp, *tmfy.DynamicSnippetResourceProp:
+		case *tmfy.WAFResourceProp, *tmfy.ACLResourceProp, *tmfy.DictionaryResourceProp, *tmfy.DynamicSnippetResourceProp,
+			*tmfy.KVStoreResourceProp, *tmfy.ConfigStoreResourceProp, *tmfy.ConfigStoreEntriesResourceProp, *tmfy.SecretStoreResourceProp:
 			// Ask yes/no if in interactive mode
 			if c.Interactive {
 				yes := tmfy.YesNo(fmt.Sprintf("import %s? ", r.GetRef()))
@@ -139,10 +429,22 @@ func importService(c tmfy.Config) error {
 					continue
 				}
 			}
+			targets = append(targets, prop)
+		}
+	}
 
-			// log.Printf(`[INFO] Running "terraform import %s %s"`, r.GetRef(), r.GetIDforTFImport())
-			log.Printf(`[INFO] Running "terraform import" on %s`, r.GetRef())
-			tmfy.TerraformImport(tf, prop, tempf)
+	// Run terraform import for the selected resources
+	if c.Parallelism > 1 {
+		log.Printf(`[INFO] Running "terraform import" on %d resources with parallelism %d`, len(targets), c.Parallelism)
+		err = tmfy.TerraformImportParallel(tf, targets, tempf, c.Parallelism)
+		if err != nil {
+			return err
+		}
+	} else {
+		for _, prop := range targets {
+			// log.Printf(`[INFO] Running "terraform import %s %s"`, prop.GetRef(), prop.GetIDforTFImport())
+			log.Printf(`[INFO] Running "terraform import" on %s`, prop.GetRef())
+			err = tmfy.TerraformImport(tf, prop, tempf)
 			if err != nil {
 				return err
 			}
@@ -159,7 +461,11 @@ func importService(c tmfy.Config) error {
 
 	// Get the config represented in HCL from the "terraform show" output
 	log.Print(`[INFO] Running "terraform show" to get the current Terraform state in HCL format`)
-	rawHCL, err = tf.ShowPlanFileRaw(context.Background(), "terraform.tfstate")
+	rawHCL, err = tmfy.TerraformShow(tf)
+	if err != nil {
+		return err
+	}
+	imported := append([]tmfy.TFBlockProp{serviceProp}, targets...)
 
 	// Make changes to the configuration
 	// log.Print("[INFO] Parsing the HCL and making corrections removing read-only attrs and replacing embedded VCL/logformat with the file function")
@@ -168,68 +474,214 @@ func importService(c tmfy.Config) error {
 	if err != nil {
 		return err
 	}
+	tfconf = tfconf.SelectProps(imported...)
 
-	result, err := tfconf.RewriteResources(serviceProp, c)
+	curState, err := tmfy.PullTFState(tf)
+	if err != nil {
+		return err
+	}
+	result, err := tfconf.RewriteResources(serviceProp, curState, c)
 	if err != nil {
 		return err
 	}
 
-	log.Print("[INFO] Writing the configuration to main.tf")
-	path := filepath.Join(c.Directory, "main.tf")
+	filename := "main.tf"
+	if c.Into {
+		filename = serviceProp.GetNormalizedName() + ".tf"
+	}
+	log.Printf("[INFO] Writing the configuration to %s", filename)
+	path := filepath.Join(c.Directory, filename)
 	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
 	defer f.Close()
 	f.Write(result)
 
-	log.Print(`[INFO] Fixing "activate" attributes in terraform.tfstate`)
-	curState, err := tmfy.LoadTFState(c.Directory)
-	if err != nil {
-		return err
-	}
-	newState, err := curState.SetActivateAttr()
+	log.Print(`[INFO] Fixing "activate" attributes in the state`)
+	newState, err := curState.SetActivateAttr(imported...)
 	if err != nil {
 		return err
 	}
 
 	if c.ManageAll {
 		log.Print(`[INFO] Settting manage_* attributes`)
-		newState, err = newState.SetManageAttrs()
+		newState, err = newState.SetManageAttrs(imported...)
 		if err != nil {
 			return err
 		}
 	}
 
-	for _, prop := range props {
+	indexed := targets
+	if c.MergeForEach {
+		log.Print(`[INFO] Merging the ACL, dictionary and dynamic snippet resources in the state`)
+		newState, indexed, err = newState.MergeForEachInstances(serviceProp, targets...)
+		if err != nil {
+			return err
+		}
+	}
+	for _, prop := range indexed {
 		switch r := prop.(type) {
-		case *tmfy.ACLResourceProp, *tmfy.DictionaryResourceProp, *tmfy.DynamicSnippetResourceProp:
-			log.Printf(`[INFO] Setting index keys in terraform.tfstate for %s`, r.GetRef())
-			newStateWithTmpl, err := newState.AddIndexKeyQueryTemplate(tmfy.SetIndexKeyQueryTmpl)
+		case *tmfy.ACLResourceProp, *tmfy.DictionaryResourceProp, *tmfy.DynamicSnippetResourceProp, *tmfy.ConfigStoreEntriesResourceProp:
+			log.Printf(`[INFO] Setting index keys in the state for %s`, r.GetRef())
+			newState, err = newState.SetIndexKey(r.GetType(), r.GetNormalizedName(), r.GetName())
 			if err != nil {
 				return err
 			}
+		}
+	}
+
+	log.Print(`[INFO] Running "terraform state push" to write the state`)
+	err = tmfy.PushTFState(tf, newState)
+	if err != nil {
+		return err
+	}
+
+	if c.WithTLS {
+		err = importTLSResources(tf, serviceProp, namer, c)
+		if err != nil {
+			return err
+		}
+		newState, err = tmfy.PullTFState(tf)
+		if err != nil {
+			return err
+		}
+	}
 
-			newState, err = newStateWithTmpl.Query(tmfy.IndexKeyQueryParams{
-				ResourceType: r.GetType(),
-				ResourceName: r.GetNormalizedName(),
-				Name:         r.GetName(),
-			})
+	if c.MovedFrom != "" {
+		log.Printf(`[INFO] Comparing resource addresses with the state in %s`, c.MovedFrom)
+		oldState, err := tmfy.LoadTFState(c.MovedFrom)
+		if err != nil {
+			return err
+		}
+		filename := "moved.tf"
+		if c.Into {
+			// Only the resources imported by this run are moved, and the moved.tf of the root module is left as it is
+			filename = c.FileDir + "_moved.tf"
+			newState, err = newState.SelectResources(tmfy.NewAddresses(before, newState.ResourceAddresses()))
 			if err != nil {
 				return err
 			}
 		}
+		moved, n, err := tmfy.BuildMovedBlocks(oldState, newState)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			log.Printf("[INFO] Writing %d moved blocks to %s", n, filename)
+			path = filepath.Join(c.Directory, filename)
+			if err := os.WriteFile(path, moved, 0644); err != nil {
+				return err
+			}
+		}
 	}
 
-	path = filepath.Join(c.Directory, "terraform.tfstate")
-	f, err = os.OpenFile(path, os.O_RDWR|os.O_TRUNC, 0644)
-	f.Write(newState.Bytes())
-	f.Close()
-
 	log.Print(`[INFO] Running "terraform refresh" to format the state file and check errors`)
-	err = tmfy.TerraformRefresh(tf)
+	return tmfy.TerraformRefresh(tf)
+}
+
+func importTLSResources(tf *tfexec.Terraform, serviceProp *tmfy.VCLServiceResourceProp, namer *tmfy.Namer, c tmfy.Config) error {
+	rawHCL, err := tmfy.TerraformShow(tf)
+	if err != nil {
+		return err
+	}
+	tfconf, err := tmfy.LoadTFConf(rawHCL)
+	if err != nil {
+		return err
+	}
+	domains, err := tfconf.GetServiceDomains(serviceProp)
+	if err != nil {
+		return err
+	}
+
+	log.Print("[INFO] Looking up TLS resources bound to the domains of the service")
+	client := tmfy.NewFastlyClient(os.Getenv("FASTLY_API_KEY"))
+	props, err := client.DiscoverTLSResources(domains, serviceProp)
+	if err != nil {
+		return err
+	}
+	if len(props) == 0 {
+		log.Print("[INFO] No TLS resources found")
+		return nil
+	}
+	if err := namer.Assign(props...); err != nil {
+		return err
+	}
+
+	// Create temp*.tf with empty TLS resource blocks
+	tempf, err := tmfy.CreateTempFile(c)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tempf.Name())
+
+	targets := make([]tmfy.TFBlockProp, 0, len(props))
+	for _, prop := range props {
+		if c.Interactive {
+			yes := tmfy.YesNo(fmt.Sprintf("import %s? ", prop.GetRef()))
+			if !yes {
+				continue
+			}
+		}
+		targets = append(targets, prop)
+	}
+
+	if c.Parallelism > 1 {
+		log.Printf(`[INFO] Running "terraform import" on %d resources with parallelism %d`, len(targets), c.Parallelism)
+		err = tmfy.TerraformImportParallel(tf, targets, tempf, c.Parallelism)
+		if err != nil {
+			return err
+		}
+	} else {
+		for _, prop := range targets {
+			log.Printf(`[INFO] Running "terraform import" on %s`, prop.GetRef())
+			err = tmfy.TerraformImport(tf, prop, tempf)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tempf.Close(); err != nil {
+		return err
+	}
+	if err := os.Remove(tempf.Name()); err != nil {
+		return err
+	}
+
+	log.Print(`[INFO] Running "terraform show" to get the TLS resources in HCL format`)
+	rawHCL, err = tmfy.TerraformShow(tf)
+	if err != nil {
+		return err
+	}
+	tfconf, err = tmfy.LoadTFConf(rawHCL)
+	if err != nil {
+		return err
+	}
+	tfconf = tfconf.SelectProps(append(targets, serviceProp)...)
+	result, err := tfconf.RewriteTLSResources(serviceProp, c)
 	if err != nil {
 		return err
 	}
 
-	fmt.Fprintln(os.Stderr)
-	fmt.Fprintln(os.Stderr, tmfy.BoldGreen("Completed!"))
-	return nil
+	filename := "tls.tf"
+	if c.Into {
+		filename = serviceProp.GetNormalizedName() + "_tls.tf"
+	}
+	log.Printf("[INFO] Writing the TLS configuration to %s", filename)
+	path := filepath.Join(c.Directory, filename)
+	return os.WriteFile(path, result, 0644)
+}
+
+// loadFileLayouts returns the layouts under "layout" in the config file, overridden by the ones given with --layout
+func loadFileLayouts(settings []string) (map[string]string, error) {
+	layouts := viper.GetStringMapString("layout")
+	if err := tmfy.ValidateFileLayouts(layouts); err != nil {
+		return nil, fmt.Errorf("%s: %w", viper.ConfigFileUsed(), err)
+	}
+	flagLayouts, err := tmfy.ParseFileLayouts(settings)
+	if err != nil {
+		return nil, err
+	}
+	for kind, layout := range flagLayouts {
+		layouts[kind] = layout
+	}
+	return layouts, nil
 }
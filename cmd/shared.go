@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	tmfy "github.com/hrmsk66/terraformify/lib"
+	"github.com/spf13/cobra"
+)
+
+// sharedCmd represents the shared-content command
+var sharedCmd = &cobra.Command{
+	Use:   "shared-content",
+	Short: "Report the VCL, log formats and response contents that the services in the working directory share",
+	Long: `Report the VCL, log formats and response contents that the services in the working directory share.
+Blocks with the same name whose content differs between the services are reported as diverged.`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filter := tmfy.CreateLogFilter()
+		log.SetOutput(filter)
+
+		workingDir, err := cmd.Flags().GetString("working-dir")
+		if err != nil {
+			return err
+		}
+		format, err := cmd.Flags().GetString("format")
+		if err != nil {
+			return err
+		}
+
+		report, err := tmfy.BuildSharedContentReport(workingDir)
+		if err != nil {
+			return err
+		}
+
+		var out []byte
+		switch format {
+		case "text":
+			out = report.Text()
+		case "json":
+			out, err = json.MarshalIndent(report, "", "  ")
+			if err != nil {
+				return err
+			}
+			out = append(out, '\n')
+		default:
+			return fmt.Errorf("unknown format %q: must be one of text, json", format)
+		}
+		_, err = os.Stdout.Write(out)
+		return err
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(sharedCmd)
+
+	// Persistent flags
+	sharedCmd.PersistentFlags().StringP("format", "f", "text", "Output format: text or json")
+}
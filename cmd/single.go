@@ -0,0 +1,281 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-exec/tfexec"
+	tmfy "github.com/hrmsk66/terraformify/lib"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// singleResource describes the associated resource imported by the dictionary and acl commands
+type singleResource struct {
+	// Type of the nested block of the service, and its ID attribute
+	BlockType string
+	IDName    string
+	// Noun of the resource in the help, e.g. dictionary items
+	Noun    string
+	NewProp func(id, name string, sr *tmfy.VCLServiceResourceProp) tmfy.TFBlockProp
+}
+
+var dictionaryResource = singleResource{
+	BlockType: "dictionary",
+	IDName:    "dictionary_id",
+	Noun:      "dictionary items",
+	NewProp: func(id, name string, sr *tmfy.VCLServiceResourceProp) tmfy.TFBlockProp {
+		return tmfy.NewDictionaryResourceProp(id, name, sr)
+	},
+}
+
+var aclResource = singleResource{
+	BlockType: "acl",
+	IDName:    "acl_id",
+	Noun:      "ACL entries",
+	NewProp: func(id, name string, sr *tmfy.VCLServiceResourceProp) tmfy.TFBlockProp {
+		return tmfy.NewACLResourceProp(id, name, sr)
+	},
+}
+
+// newSingleResourceCmd returns the command that imports only the named resource of the kind
+func newSingleResourceCmd(r singleResource) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   fmt.Sprintf("%s <service-id> <name>", r.BlockType),
+		Short: fmt.Sprintf("Import a single %s resource to merge into an existing configuration", r.Noun),
+		Long: fmt.Sprintf(`Import a single %s resource to merge into an existing configuration.
+The HCL snippet, and the state operations to merge the imported resource, are printed.
+The service resource is looked up by ID in the state of the working directory unless --resource-name is given.`, r.Noun),
+		Args:         cobra.ExactArgs(2),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			filter := tmfy.CreateLogFilter()
+			log.SetOutput(filter)
+			log.Printf("[INFO] CLI version: %s", version)
+
+			workingDir, err := cmd.Flags().GetString("working-dir")
+			if err != nil {
+				return err
+			}
+
+			apiKey := viper.GetString("api-key")
+			err = os.Setenv("FASTLY_API_KEY", apiKey)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			version, err := cmd.Flags().GetInt("version")
+			if err != nil {
+				return err
+			}
+			manageAll, err := cmd.Flags().GetBool("manage-all")
+			if err != nil {
+				return err
+			}
+			nameTemplate, err := cmd.Flags().GetString("name-template")
+			if err != nil {
+				return err
+			}
+			resourceName, err := cmd.Flags().GetString("resource-name")
+			if err != nil {
+				return err
+			}
+			// The existing configuration to merge the resource into
+			target, err := tmfy.TerraformInstall(workingDir)
+			if err != nil {
+				return err
+			}
+			if resourceName == "" {
+				resourceName, err = lookupServiceResourceName(target, args[0])
+				if err != nil {
+					return err
+				}
+			}
+			if err := tmfy.ValidateResourceName(resourceName); err != nil {
+				return err
+			}
+
+			c := tmfy.Config{
+				ID:           args[0],
+				Version:      version,
+				Directory:    workingDir,
+				ManageAll:    manageAll,
+				NameTemplate: nameTemplate,
+				ResourceName: resourceName,
+			}
+			return importSingleResource(os.Stdout, target, r, args[1], c)
+		},
+	}
+
+	// Persistent flags
+	cmd.PersistentFlags().IntP("version", "v", 0, "Version of the service to be imported")
+	cmd.PersistentFlags().BoolP("manage-all", "m", false, "Manage the items of the resource")
+	cmd.PersistentFlags().String("name-template", tmfy.DefaultNameTemplate, "Template for resource names (e.g. {{.Type}}_{{.Name}})")
+	cmd.PersistentFlags().String("resource-name", "", "Resource name of the service in the existing configuration")
+	return cmd
+}
+
+// lookupServiceResourceName returns the name of the service resource in the state of the working directory,
+// pulled from its backend
+func lookupServiceResourceName(tf *tfexec.Terraform, serviceID string) (string, error) {
+	tfstate, err := tmfy.PullTFState(tf)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the state of %s: %w. Specify the resource name of the service with --resource-name", tf.WorkingDir(), err)
+	}
+	name, ok := tfstate.ResourceNameByID("fastly_service_vcl", serviceID)
+	if !ok {
+		return "", fmt.Errorf("service %s is not found in the state of %s. Specify the resource name of the service with --resource-name", serviceID, tf.WorkingDir())
+	}
+	return name, nil
+}
+
+// importSingleResource imports the service and the named resource in a scratch directory,
+// and writes the rewritten resource block and the state operations to merge it.
+// With the local backend, the state that only has the resource is kept in the working directory as the source of "terraform state mv".
+func importSingleResource(w io.Writer, target *tfexec.Terraform, r singleResource, name string, c tmfy.Config) error {
+	workingDir := c.Directory
+	scratch, err := os.MkdirTemp("", "terraformify-"+r.BlockType)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(scratch)
+	c.Directory = scratch
+
+	log.Printf("[INFO] Initializing Terraform")
+	tf, err := tmfy.TerraformInstall(scratch)
+	if err != nil {
+		return err
+	}
+	tempf, err := tmfy.CreateInitTerraformFiles(c)
+	if err != nil {
+		return err
+	}
+	defer tempf.Close()
+
+	log.Printf(`[INFO] Running "terraform init"`)
+	err = tmfy.TerraformInit(tf, c)
+	if err != nil {
+		return err
+	}
+
+	serviceProp := tmfy.NewVCLServiceResourceProp(c.ID, "service", c.Version)
+	serviceProp.SetNormalizedName(c.ResourceName)
+	log.Printf(`[INFO] Running "terraform import" on %s`, serviceProp.GetRef())
+	err = tmfy.TerraformImport(tf, serviceProp, tempf)
+	if err != nil {
+		return err
+	}
+
+	rawHCL, err := tmfy.TerraformShow(tf)
+	if err != nil {
+		return err
+	}
+	tfconf, err := tmfy.LoadTFConf(rawHCL)
+	if err != nil {
+		return err
+	}
+	id, err := tfconf.GetNestedBlockID(serviceProp, r.BlockType, r.IDName, name)
+	if err != nil {
+		return err
+	}
+
+	namer, err := tmfy.NewNamer(c.NameTemplate)
+	if err != nil {
+		return err
+	}
+	// Keep the resource from taking the address of one in the existing configuration, which "terraform state mv" would overwrite
+	addrs, err := tmfy.ExistingResourceAddresses(target)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if i := strings.Index(addr, "."); i > 0 {
+			namer.Reserve(addr[:i], addr[i+1:])
+		}
+	}
+	prop := r.NewProp(id, name, serviceProp)
+	if err := namer.Assign(prop); err != nil {
+		return err
+	}
+
+	log.Printf(`[INFO] Running "terraform import" on %s`, prop.GetRef())
+	err = tmfy.TerraformImport(tf, prop, tempf)
+	if err != nil {
+		return err
+	}
+
+	rawHCL, err = tf.ShowPlanFileRaw(context.Background(), "terraform.tfstate")
+	if err != nil {
+		return err
+	}
+	tfconf, err = tmfy.LoadTFConf(rawHCL)
+	if err != nil {
+		return err
+	}
+	tfconf = tfconf.SelectResources(func(resourceType, name string) bool {
+		return resourceType == prop.GetType() && name == prop.GetNormalizedName()
+	})
+	curState, err := tmfy.LoadTFState(scratch)
+	if err != nil {
+		return err
+	}
+	result, err := tfconf.RewriteResources(serviceProp, curState, c)
+	if err != nil {
+		return err
+	}
+
+	if c.ManageAll {
+		curState, err = curState.SetManageAttrs()
+		if err != nil {
+			return err
+		}
+	}
+	log.Printf(`[INFO] Setting index keys in terraform.tfstate for %s`, prop.GetRef())
+	newState, err := curState.SetIndexKey(prop.GetType(), prop.GetNormalizedName(), prop.GetName())
+	if err != nil {
+		return err
+	}
+	// The state of the service, which has the credentials of the logging endpoints, is not written out
+	newState, err = newState.SelectResources([]string{prop.GetRef()})
+	if err != nil {
+		return err
+	}
+
+	// "terraform state mv" only writes to local state files, so the state is of no use with a remote backend
+	backend, err := tmfy.ConfiguredBackend(workingDir)
+	if err != nil {
+		return err
+	}
+	localBackend := backend == "" || backend == "local"
+	stateFile := fmt.Sprintf("%s_%s.tfstate", prop.GetType(), prop.GetNormalizedName())
+	if localBackend {
+		log.Printf("[INFO] Writing the state of %s to %s", prop.GetRef(), stateFile)
+		if err := os.WriteFile(filepath.Join(workingDir, stateFile), newState.Bytes(), 0644); err != nil {
+			return err
+		}
+	}
+
+	addr := fmt.Sprintf("%s[%s]", prop.GetRef(), strconv.Quote(prop.GetName()))
+	fmt.Fprintln(w, "# Add the following resource to the configuration")
+	fmt.Fprintln(w)
+	w.Write(result)
+	fmt.Fprintln(w)
+	if localBackend {
+		fmt.Fprintln(w, "# Then move the imported resource into the state")
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "terraform state mv -state=%s -state-out=terraform.tfstate '%s' '%s'\n", stateFile, addr, addr)
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "# or import it again at the address")
+	} else {
+		fmt.Fprintf(w, "# Then import it again at the address into the state in the %s backend\n", backend)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "terraform import '%s' %s\n", addr, prop.GetIDforTFImport())
+	return nil
+}
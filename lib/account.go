@@ -0,0 +1,182 @@
+package terraformify
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+// Resource types that are generated by the account command
+var accountResourceTypes = map[string]bool{
+	"fastly_user":                  true,
+	"fastly_service_authorization": true,
+	"fastly_custom_dashboard":      true,
+}
+
+// DiscoverAccountResources lists the users, service authorizations and custom dashboards of the account
+func (c *FastlyClient) DiscoverAccountResources() ([]TFBlockProp, error) {
+	var customer struct {
+		ID string `json:"id"`
+	}
+	if err := c.get("/current_customer", nil, &customer); err != nil {
+		return nil, err
+	}
+
+	var users []struct {
+		ID    string `json:"id"`
+		Login string `json:"login"`
+	}
+	if err := c.get(fmt.Sprintf("/customer/%s/users", url.PathEscape(customer.ID)), nil, &users); err != nil {
+		return nil, err
+	}
+
+	props := make([]TFBlockProp, 0, len(users))
+	logins := make(map[string]string, len(users))
+	for _, u := range users {
+		props = append(props, NewUserResourceProp(u.ID, u.Login))
+		logins[u.ID] = u.Login
+	}
+
+	rs, err := c.listJSONAPI("/service-authorizations", nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rs {
+		var userID, serviceID string
+		if ids := r.Relationships["user"].IDs(); len(ids) > 0 {
+			userID = ids[0]
+		}
+		if ids := r.Relationships["service"].IDs(); len(ids) > 0 {
+			serviceID = ids[0]
+		}
+		user := logins[userID]
+		if user == "" {
+			user = userID
+		}
+		props = append(props, NewServiceAuthorizationResourceProp(r.ID, user+"_"+serviceID))
+	}
+
+	dashboards, err := c.listDashboards()
+	if err != nil {
+		return nil, err
+	}
+	props = append(props, dashboards...)
+
+	return props, nil
+}
+
+func (c *FastlyClient) listDashboards() ([]TFBlockProp, error) {
+	props := make([]TFBlockProp, 0)
+	query := url.Values{}
+	for {
+		var page struct {
+			Data []struct {
+				ID   string `json:"id"`
+				Name string `json:"name"`
+			} `json:"data"`
+			Meta struct {
+				NextCursor string `json:"next_cursor"`
+			} `json:"meta"`
+		}
+		err := c.get("/observability/dashboards", query, &page)
+		if errors.Is(err, ErrAPINotFound) {
+			// Custom dashboards are not available for the account
+			return props, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		for _, d := range page.Data {
+			props = append(props, NewCustomDashboardResourceProp(d.ID, d.Name))
+		}
+		if page.Meta.NextCursor == "" {
+			return props, nil
+		}
+		query.Set("cursor", page.Meta.NextCursor)
+	}
+}
+
+// RewriteAccountResources returns the configuration of the account-level resources in tfconf.
+// Read-only attributes are removed, and service_id and user_id are replaced with references
+// to the services and users in tfstate, which is the state of the directory pulled from its backend.
+func (tfconf *TFConf) RewriteAccountResources(tfstate *TFState) ([]byte, error) {
+	refs, err := tfstate.idReferences("fastly_service_vcl", "fastly_service_compute", "fastly_user")
+	if err != nil {
+		return nil, err
+	}
+
+	accountconf := tfconf.SelectResources(func(resourceType, name string) bool {
+		return accountResourceTypes[resourceType]
+	})
+
+	for _, block := range accountconf.Body().Blocks() {
+		switch block.Labels()[0] {
+		case "fastly_user":
+			rewriteUserResource(block)
+		case "fastly_service_authorization":
+			err := rewriteServiceAuthorizationResource(block, refs)
+			if err != nil {
+				return nil, err
+			}
+		case "fastly_custom_dashboard":
+			rewriteCustomDashboardResource(block)
+		}
+	}
+
+	return accountconf.Bytes(), nil
+}
+
+func rewriteUserResource(block *hclwrite.Block) {
+	// remove read-only attributes
+	block.Body().RemoveAttribute("id")
+}
+
+func rewriteServiceAuthorizationResource(block *hclwrite.Block, refs map[string]hcl.Traversal) error {
+	body := block.Body()
+	// remove read-only attributes
+	body.RemoveAttribute("id")
+
+	// set service_id and user_id to represent the resource dependencies
+	for _, key := range []string{"service_id", "user_id"} {
+		id, err := getStringAttributeValue(block, key)
+		if err != nil {
+			return err
+		}
+		if ref, ok := refs[id]; ok {
+			body.SetAttributeTraversal(key, ref)
+		}
+	}
+	return nil
+}
+
+func rewriteCustomDashboardResource(block *hclwrite.Block) {
+	// remove read-only attributes
+	block.Body().RemoveAttribute("id")
+}
+
+// idReferences maps the IDs of the resources of the given types to the traversals that refer to them
+func (s *TFState) idReferences(resourceTypes ...string) (map[string]hcl.Traversal, error) {
+	addrs, err := s.instanceAddressesByID()
+	if err != nil {
+		return nil, err
+	}
+	types := make(map[string]bool, len(resourceTypes))
+	for _, t := range resourceTypes {
+		types[t] = true
+	}
+
+	refs := make(map[string]hcl.Traversal)
+	for key, addr := range addrs {
+		if !types[addr.Type] {
+			continue
+		}
+		// The keys are "<resource type>/<Fastly ID>"
+		id := strings.TrimPrefix(key, addr.Type+"/")
+		refs[id] = append(addr.Traversal(), hcl.TraverseAttr{Name: "id"})
+	}
+	return refs, nil
+}
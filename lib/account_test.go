@@ -0,0 +1,93 @@
+package terraformify
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+const accountRawHCL = `# fastly_user.alice:
+resource "fastly_user" "alice" {
+  id    = "u1"
+  login = "alice@example.com"
+  name  = "Alice"
+  role  = "engineer"
+}
+
+# fastly_service_authorization.alice_svc1:
+resource "fastly_service_authorization" "alice_svc1" {
+  id         = "a1"
+  permission = "full"
+  service_id = "svc1"
+  user_id    = "u1"
+}
+
+# fastly_service_authorization.bob_svc2:
+resource "fastly_service_authorization" "bob_svc2" {
+  id         = "a2"
+  permission = "read_only"
+  service_id = "svc2"
+  user_id    = "u2"
+}
+`
+
+func TestRewriteAccountResources(t *testing.T) {
+	tfstate := parseTestState(t, `{"resources": [
+		{"mode": "managed", "type": "fastly_service_vcl", "name": "service", "instances": [{"attributes": {"id": "svc1"}}]},
+		{"mode": "managed", "type": "fastly_user", "name": "alice", "instances": [{"attributes": {"id": "u1"}}]},
+		{"mode": "managed", "type": "fastly_service_authorization", "name": "alice_svc1", "instances": [{"attributes": {"id": "a1"}}]},
+		{"mode": "managed", "type": "fastly_service_authorization", "name": "bob_svc2", "instances": [{"attributes": {"id": "a2"}}]}]}`)
+	tfconf, err := LoadTFConf(accountRawHCL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := tfconf.RewriteAccountResources(tfstate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"service_id = fastly_service_vcl.service.id",
+		"user_id    = fastly_user.alice.id",
+		// The service and the user not managed in the directory are left as IDs
+		`service_id = "svc2"`,
+		`user_id    = "u2"`,
+	} {
+		if !strings.Contains(string(result), want) {
+			t.Errorf("%q is not found in:\n%s", want, result)
+		}
+	}
+	if strings.Contains(string(result), `id    = "u1"`) {
+		t.Errorf("the read-only id is left:\n%s", result)
+	}
+}
+
+func TestIDReferences(t *testing.T) {
+	tfstate := parseTestState(t, `{"resources": [
+		{"mode": "managed", "type": "fastly_service_vcl", "name": "service", "instances": [{"attributes": {"id": "svc1"}}]},
+		{"mode": "managed", "type": "fastly_kvstore", "name": "kv", "instances": [{"index_key": "kv", "attributes": {"id": "kv1"}}]},
+		{"mode": "managed", "type": "fastly_user", "name": "alice", "instances": [{"attributes": {"id": "u1"}}]}]}`)
+
+	refs, err := tfstate.idReferences("fastly_service_vcl", "fastly_kvstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"svc1": "fastly_service_vcl.service.id",
+		"kv1":  `fastly_kvstore.kv["kv"].id`,
+	}
+	if len(refs) != len(expected) {
+		t.Errorf("got %d references, want %d", len(refs), len(expected))
+	}
+	for id, want := range expected {
+		ref, ok := refs[id]
+		if !ok {
+			t.Errorf("%s is not found", id)
+			continue
+		}
+		if got := strings.TrimSpace(string(hclwrite.TokensForTraversal(ref).Bytes())); got != want {
+			t.Errorf("%s: got %s, want %s", id, got, want)
+		}
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"io"
 	"log"
 	"os"
+	"path"
+	"path/filepath"
 	"strings"
 
 	"github.com/fatih/color"
@@ -14,11 +16,47 @@ import (
 )
 
 type Config struct {
-	ID          string
-	Version     int
-	Directory   string
-	Interactive bool
-	ManageAll   bool
+	ID               string
+	Version          int
+	Directory        string
+	Interactive      bool
+	ManageAll        bool
+	Parallelism      int
+	MovedFrom        string
+	NameTemplate     string
+	ResourceName     string
+	ExtractThreshold int
+	WithTLS          bool
+	// Backend is the type of the backend to keep the state in, e.g. s3. The local backend is used if empty.
+	Backend       string
+	BackendConfig map[string]string
+	// Into is set when importing into an existing root module, and Workspace selects the workspace of it
+	Into      bool
+	Workspace string
+	// FileDir is the directory, relative to Directory, to write the extracted files to
+	FileDir string
+	// MergeForEach merges the ACL, dictionary and dynamic snippet resources into one for_each resource per type
+	MergeForEach bool
+	// LogFormatTemplates writes the log formats with the values specific to the environment as templates
+	LogFormatTemplates bool
+	// Dedupe writes the VCL, log formats and response contents to the common directory, one copy for the same content
+	Dedupe bool
+	// FileLayouts overrides DefaultFileLayouts for the kinds in it
+	FileLayouts map[string]string
+
+	// written has the hashes of the contents of the extracted files keyed by their paths relative to Directory.
+	// RewriteResources sets it to tell the files that a layout maps to the same path.
+	written map[string]string
+}
+
+// fileDir returns the directory to write the extracted files to
+func (c Config) fileDir() string {
+	return filepath.Join(c.Directory, c.FileDir)
+}
+
+// filePath returns the path of the extracted file relative to the module, as referred to with path.module in the configuration
+func (c Config) filePath(fileType, name string) string {
+	return path.Join(c.FileDir, fileType, name)
 }
 
 var Bold = color.New(color.Bold).SprintFunc()
@@ -38,6 +76,27 @@ func CreateLogFilter() io.Writer {
 	return filter
 }
 
+// ValidateResourceName returns an error if the name cannot be used as a Terraform resource name
+func ValidateResourceName(name string) error {
+	if !isValidResourceName(name) {
+		return fmt.Errorf("%q is not a valid resource name: it must begin with a letter or underscore and may contain only letters, digits, underscores, and dashes", name)
+	}
+	return nil
+}
+
+// ParseBackendConfig parses the backend settings given as key=value
+func ParseBackendConfig(settings []string) (map[string]string, error) {
+	config := make(map[string]string, len(settings))
+	for _, s := range settings {
+		i := strings.Index(s, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid backend config %q: must be key=value", s)
+		}
+		config[s[:i]] = s[i+1:]
+	}
+	return config, nil
+}
+
 func CheckDirEmpty(path string) error {
 	info, err := os.Stat(path)
 	if err != nil {
@@ -0,0 +1,72 @@
+package terraformify
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// Extensions of the files the response object content is written to, keyed by media type
+var contentTypeExtensions = map[string]string{
+	"application/javascript":   "js",
+	"application/json":         "json",
+	"application/xml":          "xml",
+	"image/gif":                "gif",
+	"image/jpeg":               "jpg",
+	"image/png":                "png",
+	"image/svg+xml":            "svg",
+	"image/vnd.microsoft.icon": "ico",
+	"image/webp":               "webp",
+	"image/x-icon":             "ico",
+	"text/css":                 "css",
+	"text/csv":                 "csv",
+	"text/html":                "html",
+	"text/javascript":          "js",
+	"text/plain":               "txt",
+	"text/xml":                 "xml",
+}
+
+// mediaTypeExtension returns the extension for the media type of the Content-Type value, or "" if it is unknown
+func mediaTypeExtension(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	if ext, ok := contentTypeExtensions[mediaType]; ok {
+		return ext
+	}
+	// Structured syntax suffixes, e.g. application/problem+json
+	if strings.HasSuffix(mediaType, "+json") {
+		return "json"
+	}
+	if strings.HasSuffix(mediaType, "+xml") {
+		return "xml"
+	}
+	return ""
+}
+
+// contentExtension returns the extension of the file to write the response object content to.
+// The content_type of the response object is used if it is known, otherwise the type is sniffed from the content.
+func contentExtension(contentType, content string) string {
+	if ext := mediaTypeExtension(contentType); ext != "" {
+		return ext
+	}
+
+	// http.DetectContentType tells neither JSON nor SVG from plain text and XML
+	trimmed := strings.TrimSpace(content)
+	if (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) && json.Valid([]byte(trimmed)) {
+		return "json"
+	}
+	detected := http.DetectContentType([]byte(content))
+	if ext := mediaTypeExtension(detected); ext != "" {
+		if ext == "xml" && strings.Contains(trimmed, "<svg") {
+			return "svg"
+		}
+		return ext
+	}
+	if strings.HasPrefix(detected, "application/octet-stream") {
+		return "bin"
+	}
+	return "txt"
+}
@@ -0,0 +1,31 @@
+package terraformify
+
+import "testing"
+
+func TestContentExtension(t *testing.T) {
+	testCases := []struct {
+		contentType string
+		content     string
+		expected    string
+	}{
+		{contentType: "text/html", content: "", expected: "html"},
+		{contentType: "text/html; charset=utf-8", content: "<p>hi</p>", expected: "html"},
+		{contentType: "application/problem+json", content: `{"title": "Forbidden"}`, expected: "json"},
+		{contentType: "image/svg+xml", content: "<svg></svg>", expected: "svg"},
+		// The declared type wins over sniffing
+		{contentType: "text/plain", content: `{"a": 1}`, expected: "txt"},
+		// Sniffed when the type is missing or unknown
+		{contentType: "", content: `{"a": 1}`, expected: "json"},
+		{contentType: "application/x-unknown", content: "<!DOCTYPE html><html></html>", expected: "html"},
+		{contentType: "", content: `<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg"></svg>`, expected: "svg"},
+		{contentType: "", content: "\x00\x00\x01\x00\x01\x00", expected: "ico"},
+		{contentType: "", content: "\x01\x02\x03", expected: "bin"},
+		{contentType: "", content: "User-Agent: *\nDisallow:\n", expected: "txt"},
+	}
+
+	for _, tt := range testCases {
+		if got := contentExtension(tt.contentType, tt.content); got != tt.expected {
+			t.Errorf("contentExtension(%q, %q) = %s, want %s", tt.contentType, tt.content, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package terraformify
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Directory, relative to the working directory, to write the deduplicated content to
+const commonDir = "common"
+
+// contentHash returns the hash that names the content in the common directory
+func contentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// ContentUsage is a nested block of a service that refers to an extracted file
+type ContentUsage struct {
+	Service string `json:"service"`
+	Kind    string `json:"kind"`
+	Name    string `json:"name"`
+	File    string `json:"file"`
+	Hash    string `json:"hash"`
+}
+
+func (u ContentUsage) String() string {
+	return fmt.Sprintf("%s %s %q", u.Service, u.Kind, u.Name)
+}
+
+// SharedContent is the content that more than one service uses
+type SharedContent struct {
+	Hash   string         `json:"hash"`
+	Usages []ContentUsage `json:"usages"`
+}
+
+// DivergedContent is a block that has the same kind and name in the services, but different content
+type DivergedContent struct {
+	Kind   string         `json:"kind"`
+	Name   string         `json:"name"`
+	Usages []ContentUsage `json:"usages"`
+}
+
+type SharedContentReport struct {
+	Shared   []SharedContent   `json:"shared"`
+	Diverged []DivergedContent `json:"diverged"`
+}
+
+// BuildSharedContentReport finds the VCL, snippets, log formats and response contents that the services in dir share,
+// and those with the same name whose content differs between the services.
+// The content is compared by hash, so the report works whether or not it was deduplicated.
+func BuildSharedContentReport(dir string) (*SharedContentReport, error) {
+	usages, err := contentUsages(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byHash := make(map[string][]ContentUsage)
+	byName := make(map[string][]ContentUsage)
+	for _, u := range usages {
+		byHash[u.Hash] = append(byHash[u.Hash], u)
+		key := u.Kind + "\x00" + u.Name
+		byName[key] = append(byName[key], u)
+	}
+
+	report := &SharedContentReport{Shared: make([]SharedContent, 0), Diverged: make([]DivergedContent, 0)}
+	for hash, us := range byHash {
+		if countServices(us) > 1 {
+			report.Shared = append(report.Shared, SharedContent{Hash: hash, Usages: us})
+		}
+	}
+	for _, us := range byName {
+		hashes := make(map[string]bool)
+		for _, u := range us {
+			hashes[u.Hash] = true
+		}
+		if len(hashes) > 1 {
+			report.Diverged = append(report.Diverged, DivergedContent{Kind: us[0].Kind, Name: us[0].Name, Usages: us})
+		}
+	}
+	sort.Slice(report.Shared, func(i, j int) bool {
+		return report.Shared[i].Usages[0].File < report.Shared[j].Usages[0].File
+	})
+	sort.Slice(report.Diverged, func(i, j int) bool {
+		if report.Diverged[i].Kind != report.Diverged[j].Kind {
+			return report.Diverged[i].Kind < report.Diverged[j].Kind
+		}
+		return report.Diverged[i].Name < report.Diverged[j].Name
+	})
+	return report, nil
+}
+
+func countServices(usages []ContentUsage) int {
+	services := make(map[string]bool)
+	for _, u := range usages {
+		services[u.Service] = true
+	}
+	return len(services)
+}
+
+// contentUsages returns the nested blocks of the services, and the dynamic snippet resources, that refer to extracted files
+func contentUsages(dir string) ([]ContentUsage, error) {
+	files, err := loadConfigFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	locals := localMaps(configBodies(files)...)
+
+	usages := make([]ContentUsage, 0)
+	add := func(service, kind, name, file string) error {
+		if file == "" {
+			return nil
+		}
+		b, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			return err
+		}
+		usages = append(usages, ContentUsage{Service: service, Kind: kind, Name: name, File: file, Hash: contentHash(b)})
+		return nil
+	}
+
+	for _, f := range files {
+		for _, block := range f.Body().Blocks() {
+			labels := block.Labels()
+			if block.Type() != "resource" || len(labels) != 2 {
+				continue
+			}
+			switch labels[0] {
+			case "fastly_service_vcl":
+				service := labels[0] + "." + labels[1]
+				for _, nested := range block.Body().Blocks() {
+					t := nested.Type()
+					key := "content"
+					if strings.HasPrefix(t, "logging_") {
+						key = "format"
+					} else if t != "vcl" && t != "snippet" && t != "response_object" {
+						continue
+					}
+					if err := add(service, t, stringAttr(nested, "name"), fileReference(nested, key)); err != nil {
+						return nil, err
+					}
+				}
+			case "fastly_service_dynamic_snippet_content":
+				s, ok := parseForEach(block, locals)
+				if !ok {
+					continue
+				}
+				for _, name := range s.names() {
+					if err := add(s.service, "dynamicsnippet", name, fileReference(s.blocks[name], "content")); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+	}
+	return usages, nil
+}
+
+// Text renders the report for the terminal
+func (r *SharedContentReport) Text() []byte {
+	var b strings.Builder
+	if len(r.Shared) == 0 {
+		b.WriteString("No content is shared between the services\n")
+	} else {
+		b.WriteString(Bold("Shared content") + "\n")
+		for _, s := range r.Shared {
+			fmt.Fprintf(&b, "  %s\n", s.Usages[0].File)
+			for _, u := range s.Usages {
+				if u.File == s.Usages[0].File {
+					fmt.Fprintf(&b, "    %s\n", u)
+				} else {
+					fmt.Fprintf(&b, "    %s: %s\n", u, u.File)
+				}
+			}
+		}
+	}
+	if len(r.Diverged) > 0 {
+		b.WriteString("\n" + BoldYellow("Diverged content") + "\n")
+		for _, d := range r.Diverged {
+			fmt.Fprintf(&b, "  %s %q\n", d.Kind, d.Name)
+			for _, u := range d.Usages {
+				fmt.Fprintf(&b, "    %s: %s (%s)\n", u.Service, u.File, u.Hash)
+			}
+		}
+	}
+	return []byte(b.String())
+}
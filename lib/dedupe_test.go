@@ -0,0 +1,110 @@
+package terraformify
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteFileDedupe(t *testing.T) {
+	dir := t.TempDir()
+	c := Config{Directory: dir, FileDir: "www", Dedupe: true}
+
+	a, err := c.writeFile("snippet", FileVars{Type: "snippet", Name: "headers", Ext: "vcl"}, []byte("set req.http.X = \"1\";\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.FileDir = "api"
+	b, err := c.writeFile("dynamicsnippet", FileVars{Type: "dynamicsnippet", Name: "common_headers", Ext: "vcl"}, []byte("set req.http.X = \"1\";\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != b {
+		t.Errorf("the same content is written to %s and %s", a, b)
+	}
+	if want := "common/vcl/" + contentHash([]byte("set req.http.X = \"1\";\n")) + ".vcl"; a != want {
+		t.Errorf("got %s, want %s", a, want)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "common", "vcl", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("got %d files, want 1: %v", len(files), files)
+	}
+
+	name, err := c.writeFile("logformat", FileVars{Type: "logging_s3", Name: "weblogs", Ext: "json.tftpl"}, []byte("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(name, ".json.tftpl") {
+		t.Errorf("the extension is not kept: %s", name)
+	}
+}
+
+func TestBuildSharedContentReport(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"common/vcl/shared.vcl":   "shared",
+		"www/vcl/snippet_a.vcl":   "www version",
+		"api/vcl/snippet_a.vcl":   "api version",
+		"www/logformat/logs.json": "{}",
+		"main.tf": `resource "fastly_service_vcl" "www" {
+  snippet {
+    name    = "shared"
+    content = file("./common/vcl/shared.vcl")
+  }
+  snippet {
+    name    = "a"
+    content = file("./www/vcl/snippet_a.vcl")
+  }
+  logging_https {
+    name   = "logs"
+    format = templatefile("./www/logformat/logs.json", {})
+  }
+}
+
+resource "fastly_service_vcl" "api" {
+  snippet {
+    name    = "shared"
+    content = file("./common/vcl/shared.vcl")
+  }
+  snippet {
+    name    = "a"
+    content = file("./api/vcl/snippet_a.vcl")
+  }
+}
+
+resource "fastly_service_dynamic_snippet_content" "dsnippet" {
+  content = file("./common/vcl/shared.vcl")
+  for_each = {
+    for d in fastly_service_vcl.api.dynamicsnippet : d.name => d if d.name == "dynamic"
+  }
+}
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report, err := BuildSharedContentReport(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Shared) != 1 || len(report.Shared[0].Usages) != 3 {
+		t.Fatalf("unexpected shared content: %+v", report.Shared)
+	}
+	if u := report.Shared[0].Usages[2]; u.Service != "fastly_service_vcl.api" || u.Kind != "dynamicsnippet" || u.Name != "dynamic" {
+		t.Errorf("unexpected usage of the dynamic snippet: %+v", u)
+	}
+	if len(report.Diverged) != 1 || report.Diverged[0].Kind != "snippet" || report.Diverged[0].Name != "a" {
+		t.Errorf("unexpected diverged content: %+v", report.Diverged)
+	}
+}
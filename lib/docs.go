@@ -0,0 +1,533 @@
+package terraformify
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// ServiceDoc is the human-readable summary of a service resource in the configuration
+type ServiceDoc struct {
+	Name         string
+	Address      string
+	Domains      []DomainDoc
+	Backends     []BackendDoc
+	Healthchecks []HealthcheckDoc
+	Logging      []LoggingDoc
+	Snippets     []SnippetDoc
+	VCLs         []VCLDoc
+	Dictionaries []TableDoc
+	ACLs         []TableDoc
+}
+
+type DomainDoc struct {
+	Name    string
+	Comment string
+}
+
+type BackendDoc struct {
+	Name        string
+	Address     string
+	Port        string
+	SSL         bool
+	Shield      string
+	Healthcheck string
+}
+
+type HealthcheckDoc struct {
+	Name          string
+	Host          string
+	Path          string
+	CheckInterval string
+}
+
+type LoggingDoc struct {
+	Name        string
+	Type        string
+	Destination string
+	Credentials []string
+	FormatFile  string
+}
+
+type SnippetDoc struct {
+	Name     string
+	Type     string
+	Priority int
+	Dynamic  bool
+	File     string
+}
+
+type VCLDoc struct {
+	Name string
+	Main bool
+	File string
+}
+
+// TableDoc summarizes a dictionary or an ACL. Size is -1 if the items are not managed in the configuration.
+type TableDoc struct {
+	Name      string
+	WriteOnly bool
+	Size      int
+	File      string
+}
+
+// Attributes of logging endpoints that tell where the logs go, in order of preference
+var loggingDestinationKeys = []string{"url", "address", "hostname", "host", "endpoint", "bucket_name", "container", "topic", "dataset", "project_id", "index", "path"}
+
+// Attributes of the service that hold credentials. They are masked in the docs, and listed without the values by the secrets query.
+var credentialAttributes = []string{
+	"access_key", "account_key", "api_key", "auth_token", "passphrase", "password", "private_key",
+	"s3_access_key", "s3_secret_key", "sas_token", "secret_key", "ssl_client_key", "tls_client_key", "token", "user_key",
+}
+
+func isCredentialAttribute(key string) bool {
+	for _, k := range credentialAttributes {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+var fileFunctionPattern = regexp.MustCompile(`file\("([^"]+)"\)`)
+var forEachNamePattern = regexp.MustCompile(`d\.name\s*==\s*"([^"]*)"`)
+
+// BuildServiceDocs summarizes the service resources in the configuration.
+// workingDir is used to resolve the extracted files referred to by the configuration.
+func (tfconf *TFConf) BuildServiceDocs(workingDir string) ([]ServiceDoc, error) {
+	dictSizes, aclSizes, dsnippetFiles, err := tfconf.managedContents(workingDir)
+	if err != nil {
+		return nil, err
+	}
+
+	docs := make([]ServiceDoc, 0)
+	for _, block := range tfconf.Body().Blocks() {
+		labels := block.Labels()
+		if block.Type() != "resource" || len(labels) != 2 || labels[0] != "fastly_service_vcl" {
+			continue
+		}
+		name, err := getStringAttributeValue(block, "name")
+		if err != nil {
+			return nil, err
+		}
+		doc := ServiceDoc{Name: name, Address: labels[0] + "." + labels[1]}
+
+		for _, nested := range block.Body().Blocks() {
+			blockName := stringAttr(nested, "name")
+			switch t := nested.Type(); {
+			case t == "domain":
+				doc.Domains = append(doc.Domains, DomainDoc{Name: blockName, Comment: stringAttr(nested, "comment")})
+			case t == "backend":
+				doc.Backends = append(doc.Backends, BackendDoc{
+					Name:        blockName,
+					Address:     stringAttr(nested, "address"),
+					Port:        stringAttr(nested, "port"),
+					SSL:         stringAttr(nested, "use_ssl") == "true",
+					Shield:      stringAttr(nested, "shield"),
+					Healthcheck: stringAttr(nested, "healthcheck"),
+				})
+			case t == "healthcheck":
+				doc.Healthchecks = append(doc.Healthchecks, HealthcheckDoc{
+					Name:          blockName,
+					Host:          stringAttr(nested, "host"),
+					Path:          stringAttr(nested, "path"),
+					CheckInterval: stringAttr(nested, "check_interval"),
+				})
+			case strings.HasPrefix(t, "logging_"):
+				doc.Logging = append(doc.Logging, buildLoggingDoc(nested))
+			case t == "snippet" || t == "dynamicsnippet":
+				priority, _ := strconv.Atoi(stringAttr(nested, "priority"))
+				snippet := SnippetDoc{
+					Name:     blockName,
+					Type:     stringAttr(nested, "type"),
+					Priority: priority,
+					Dynamic:  t == "dynamicsnippet",
+					File:     fileReference(nested, "content"),
+				}
+				if snippet.Dynamic {
+					snippet.File = dsnippetFiles[nestedAddress(doc.Address, t, blockName)]
+				}
+				doc.Snippets = append(doc.Snippets, snippet)
+			case t == "vcl":
+				doc.VCLs = append(doc.VCLs, VCLDoc{
+					Name: blockName,
+					Main: stringAttr(nested, "main") == "true",
+					File: fileReference(nested, "content"),
+				})
+			case t == "dictionary":
+				table := TableDoc{Name: blockName, WriteOnly: stringAttr(nested, "write_only") == "true", Size: -1}
+				if s, ok := dictSizes[nestedAddress(doc.Address, t, blockName)]; ok {
+					table.Size, table.File = s.size, s.file
+				}
+				doc.Dictionaries = append(doc.Dictionaries, table)
+			case t == "acl":
+				table := TableDoc{Name: blockName, Size: -1}
+				if s, ok := aclSizes[nestedAddress(doc.Address, t, blockName)]; ok {
+					table.Size, table.File = s.size, s.file
+				}
+				doc.ACLs = append(doc.ACLs, table)
+			}
+		}
+
+		sort.SliceStable(doc.Snippets, func(i, j int) bool {
+			if doc.Snippets[i].Type != doc.Snippets[j].Type {
+				return doc.Snippets[i].Type < doc.Snippets[j].Type
+			}
+			return doc.Snippets[i].Priority < doc.Snippets[j].Priority
+		})
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+// nestedAddress returns the address of the nested block of the service, e.g. fastly_service_vcl.service.dictionary["name"]
+func nestedAddress(service, blockType, name string) string {
+	return fmt.Sprintf("%s.%s[%s]", service, blockType, strconv.Quote(name))
+}
+
+func buildLoggingDoc(block *hclwrite.Block) LoggingDoc {
+	doc := LoggingDoc{
+		Name:       stringAttr(block, "name"),
+		Type:       strings.TrimPrefix(block.Type(), "logging_"),
+		FormatFile: fileReference(block, "format"),
+	}
+	for _, key := range loggingDestinationKeys {
+		if v := stringAttr(block, key); v != "" {
+			doc.Destination = v
+			break
+		}
+	}
+
+	attrs := block.Body().Attributes()
+	keys := make([]string, 0, len(attrs))
+	for key := range attrs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if isCredentialAttribute(key) {
+			doc.Credentials = append(doc.Credentials, key+"=****")
+		}
+	}
+	return doc
+}
+
+type tableSize struct {
+	size int
+	file string
+}
+
+// managedContents counts the items of the dictionaries and the entries of the ACLs managed in the configuration,
+// and finds the files of the dynamic snippets. They are keyed by the addresses of the nested blocks of the services,
+// e.g. fastly_service_vcl.service.dictionary["name"], as services may have nested blocks of the same name.
+func (tfconf *TFConf) managedContents(workingDir string) (map[string]tableSize, map[string]tableSize, map[string]string, error) {
+	dictSizes := make(map[string]tableSize)
+	aclSizes := make(map[string]tableSize)
+	dsnippetFiles := make(map[string]string)
+
+	locals := localMaps(tfconf.Body())
+	for _, block := range tfconf.Body().Blocks() {
+		labels := block.Labels()
+		if block.Type() != "resource" || len(labels) != 2 {
+			continue
+		}
+		s, ok := parseForEach(block, locals)
+		if !ok {
+			continue
+		}
+
+		for _, name := range s.names() {
+			switch labels[0] {
+			case "fastly_service_dictionary_items":
+				size, file, err := countDictionaryItems(s.blocks[name], workingDir)
+				if err != nil {
+					return nil, nil, nil, err
+				}
+				dictSizes[s.target(name)] = tableSize{size: size, file: file}
+			case "fastly_service_acl_entries":
+				size, file, err := countACLEntries(s.blocks[name], workingDir)
+				if err != nil {
+					return nil, nil, nil, err
+				}
+				aclSizes[s.target(name)] = tableSize{size: size, file: file}
+			case "fastly_service_dynamic_snippet_content":
+				dsnippetFiles[s.target(name)] = fileReference(s.blocks[name], "content")
+			}
+		}
+	}
+	return dictSizes, aclSizes, dsnippetFiles, nil
+}
+
+func countDictionaryItems(block *hclwrite.Block, workingDir string) (int, string, error) {
+	if path := fileReference(block, "items"); path != "" {
+		b, err := os.ReadFile(filepath.Join(workingDir, path))
+		if err != nil {
+			return 0, path, err
+		}
+		var items map[string]interface{}
+		if err := json.Unmarshal(b, &items); err != nil {
+			return 0, path, err
+		}
+		return len(items), path, nil
+	}
+
+	v, err := getAttributeValue(block, "items")
+	if errors.Is(err, ErrAttrNotFound) {
+		return 0, "", nil
+	} else if err != nil {
+		return 0, "", err
+	}
+	if v.IsNull() || !v.CanIterateElements() {
+		return 0, "", nil
+	}
+	return v.LengthInt(), "", nil
+}
+
+func countACLEntries(block *hclwrite.Block, workingDir string) (int, string, error) {
+	for _, nested := range block.Body().Blocks() {
+		if nested.Type() != "dynamic" {
+			continue
+		}
+		path := fileReference(nested, "for_each")
+		if path == "" {
+			// The list of the entries of a merged resource
+			v, err := getAttributeValue(nested, "for_each")
+			if err != nil || v.IsNull() || !v.CanIterateElements() {
+				continue
+			}
+			return v.LengthInt(), "", nil
+		}
+		f, err := os.Open(filepath.Join(workingDir, path))
+		if err != nil {
+			return 0, path, err
+		}
+		defer f.Close()
+		records, err := csv.NewReader(f).ReadAll()
+		if err != nil {
+			return 0, path, err
+		}
+		// Exclude the header
+		if len(records) == 0 {
+			return 0, path, nil
+		}
+		return len(records) - 1, path, nil
+	}
+
+	n := 0
+	for _, nested := range block.Body().Blocks() {
+		if nested.Type() == "entry" {
+			n++
+		}
+	}
+	return n, "", nil
+}
+
+// stringAttr returns the value of the attribute as a string, or the expression itself if it cannot be evaluated
+func stringAttr(block *hclwrite.Block, key string) string {
+	attr := block.Body().GetAttribute(key)
+	if attr == nil {
+		return ""
+	}
+	v, err := getAttributeValue(block, key)
+	if err != nil || v.IsNull() || !v.IsKnown() {
+		return strings.TrimSpace(string(attr.Expr().BuildTokens(nil).Bytes()))
+	}
+	switch v.Type() {
+	case cty.String:
+		return v.AsString()
+	case cty.Bool:
+		return strconv.FormatBool(v.True())
+	case cty.Number:
+		return v.AsBigFloat().Text('f', -1)
+	}
+	return strings.TrimSpace(string(attr.Expr().BuildTokens(nil).Bytes()))
+}
+
+// fileReference returns the path in the file function of the attribute, if any
+func fileReference(block *hclwrite.Block, key string) string {
+	attr := block.Body().GetAttribute(key)
+	if attr == nil {
+		return ""
+	}
+	m := fileFunctionPattern.FindSubmatch(attr.Expr().BuildTokens(nil).Bytes())
+	if m == nil {
+		return ""
+	}
+	// Older configurations refer to the files relative to the working directory
+	p := strings.TrimPrefix(string(m[1]), "${path.module}/")
+	return strings.TrimPrefix(p, "./")
+}
+
+// RenderServiceDocs renders the docs in the format: markdown or html
+func RenderServiceDocs(docs []ServiceDoc, format string) ([]byte, error) {
+	var buf bytes.Buffer
+	switch format {
+	case "markdown":
+		tmpl, err := template.New("docs").Funcs(docsFuncs).Parse(markdownDocsTmpl)
+		if err != nil {
+			return nil, err
+		}
+		err = tmpl.Execute(&buf, docs)
+		return buf.Bytes(), err
+	case "html":
+		tmpl, err := htmltemplate.New("docs").Funcs(htmltemplate.FuncMap(docsFuncs)).Parse(htmlDocsTmpl)
+		if err != nil {
+			return nil, err
+		}
+		err = tmpl.Execute(&buf, docs)
+		return buf.Bytes(), err
+	default:
+		return nil, ValidateDocsFormat(format)
+	}
+}
+
+// ValidateDocsFormat returns an error if the docs cannot be rendered in the format
+func ValidateDocsFormat(format string) error {
+	switch format {
+	case "markdown", "html":
+		return nil
+	default:
+		return errors.New(`unknown format "` + format + `": must be one of markdown, html`)
+	}
+}
+
+var docsFuncs = template.FuncMap{
+	"size": func(t TableDoc) string {
+		if t.Size < 0 {
+			return "not managed"
+		}
+		return strconv.Itoa(t.Size)
+	},
+	"yesno": func(b bool) string {
+		if b {
+			return "yes"
+		}
+		return "no"
+	},
+	"join": strings.Join,
+	"cell": func(s string) string {
+		if s == "" {
+			return "-"
+		}
+		return strings.ReplaceAll(s, "|", `\|`)
+	},
+}
+
+const markdownDocsTmpl = `{{range $i, $s := .}}{{if $i}}
+{{end}}# {{$s.Name}}
+
+Terraform resource: ` + "`{{$s.Address}}`" + `
+{{if $s.Domains}}
+## Domains
+{{range $s.Domains}}
+- {{.Name}}{{if .Comment}} ({{.Comment}}){{end}}{{end}}
+{{end}}{{if $s.Backends}}
+## Backends
+
+| Name | Address | Port | SSL | Shield | Healthcheck |
+| --- | --- | --- | --- | --- | --- |
+{{range $s.Backends}}| {{cell .Name}} | {{cell .Address}} | {{cell .Port}} | {{yesno .SSL}} | {{cell .Shield}} | {{cell .Healthcheck}} |
+{{end}}{{end}}{{if $s.Healthchecks}}
+## Healthchecks
+
+| Name | Host | Path | Interval (ms) |
+| --- | --- | --- | --- |
+{{range $s.Healthchecks}}| {{cell .Name}} | {{cell .Host}} | {{cell .Path}} | {{cell .CheckInterval}} |
+{{end}}{{end}}{{if $s.Logging}}
+## Logging endpoints
+
+| Name | Type | Destination | Credentials | Format |
+| --- | --- | --- | --- | --- |
+{{range $s.Logging}}| {{cell .Name}} | {{.Type}} | {{cell .Destination}} | {{cell (join .Credentials ", ")}} | {{if .FormatFile}}[{{.FormatFile}}]({{.FormatFile}}){{else}}inline{{end}} |
+{{end}}{{end}}{{if $s.Snippets}}
+## Snippets
+
+| Type | Priority | Name | Dynamic | Content |
+| --- | --- | --- | --- | --- |
+{{range $s.Snippets}}| {{.Type}} | {{.Priority}} | {{cell .Name}} | {{yesno .Dynamic}} | {{if .File}}[{{.File}}]({{.File}}){{else}}-{{end}} |
+{{end}}{{end}}{{if $s.VCLs}}
+## Custom VCL
+{{range $s.VCLs}}
+- [{{.Name}}]({{.File}}){{if .Main}} (main){{end}}{{end}}
+{{end}}{{if $s.Dictionaries}}
+## Dictionaries
+
+| Name | Items | Write-only | File |
+| --- | --- | --- | --- |
+{{range $s.Dictionaries}}| {{cell .Name}} | {{size .}} | {{yesno .WriteOnly}} | {{if .File}}[{{.File}}]({{.File}}){{else}}-{{end}} |
+{{end}}{{end}}{{if $s.ACLs}}
+## ACLs
+
+| Name | Entries | File |
+| --- | --- | --- |
+{{range $s.ACLs}}| {{cell .Name}} | {{size .}} | {{if .File}}[{{.File}}]({{.File}}){{else}}-{{end}} |
+{{end}}{{end}}{{end}}`
+
+const htmlDocsTmpl = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{range $i, $s := .}}{{if $i}}, {{end}}{{$s.Name}}{{end}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; }
+</style>
+</head>
+<body>
+{{range .}}<h1>{{.Name}}</h1>
+<p>Terraform resource: <code>{{.Address}}</code></p>
+{{if .Domains}}<h2>Domains</h2>
+<ul>
+{{range .Domains}}<li>{{.Name}}{{if .Comment}} ({{.Comment}}){{end}}</li>
+{{end}}</ul>
+{{end}}{{if .Backends}}<h2>Backends</h2>
+<table>
+<tr><th>Name</th><th>Address</th><th>Port</th><th>SSL</th><th>Shield</th><th>Healthcheck</th></tr>
+{{range .Backends}}<tr><td>{{.Name}}</td><td>{{.Address}}</td><td>{{.Port}}</td><td>{{yesno .SSL}}</td><td>{{.Shield}}</td><td>{{.Healthcheck}}</td></tr>
+{{end}}</table>
+{{end}}{{if .Healthchecks}}<h2>Healthchecks</h2>
+<table>
+<tr><th>Name</th><th>Host</th><th>Path</th><th>Interval (ms)</th></tr>
+{{range .Healthchecks}}<tr><td>{{.Name}}</td><td>{{.Host}}</td><td>{{.Path}}</td><td>{{.CheckInterval}}</td></tr>
+{{end}}</table>
+{{end}}{{if .Logging}}<h2>Logging endpoints</h2>
+<table>
+<tr><th>Name</th><th>Type</th><th>Destination</th><th>Credentials</th><th>Format</th></tr>
+{{range .Logging}}<tr><td>{{.Name}}</td><td>{{.Type}}</td><td>{{.Destination}}</td><td>{{join .Credentials ", "}}</td><td>{{if .FormatFile}}<a href="{{.FormatFile}}">{{.FormatFile}}</a>{{else}}inline{{end}}</td></tr>
+{{end}}</table>
+{{end}}{{if .Snippets}}<h2>Snippets</h2>
+<table>
+<tr><th>Type</th><th>Priority</th><th>Name</th><th>Dynamic</th><th>Content</th></tr>
+{{range .Snippets}}<tr><td>{{.Type}}</td><td>{{.Priority}}</td><td>{{.Name}}</td><td>{{yesno .Dynamic}}</td><td>{{if .File}}<a href="{{.File}}">{{.File}}</a>{{end}}</td></tr>
+{{end}}</table>
+{{end}}{{if .VCLs}}<h2>Custom VCL</h2>
+<ul>
+{{range .VCLs}}<li><a href="{{.File}}">{{.Name}}</a>{{if .Main}} (main){{end}}</li>
+{{end}}</ul>
+{{end}}{{if .Dictionaries}}<h2>Dictionaries</h2>
+<table>
+<tr><th>Name</th><th>Items</th><th>Write-only</th><th>File</th></tr>
+{{range .Dictionaries}}<tr><td>{{.Name}}</td><td>{{size .}}</td><td>{{yesno .WriteOnly}}</td><td>{{if .File}}<a href="{{.File}}">{{.File}}</a>{{end}}</td></tr>
+{{end}}</table>
+{{end}}{{if .ACLs}}<h2>ACLs</h2>
+<table>
+<tr><th>Name</th><th>Entries</th><th>File</th></tr>
+{{range .ACLs}}<tr><td>{{.Name}}</td><td>{{size .}}</td><td>{{if .File}}<a href="{{.File}}">{{.File}}</a>{{end}}</td></tr>
+{{end}}</table>
+{{end}}{{end}}</body>
+</html>
+`
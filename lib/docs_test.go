@@ -0,0 +1,226 @@
+package terraformify
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuildServiceDocs(t *testing.T) {
+	b, err := os.ReadFile(goldenFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tfconf, err := LoadTFConf(string(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs, err := tfconf.BuildServiceDocs("../testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 1 {
+		t.Fatalf("got %d services, want 1", len(docs))
+	}
+
+	page, err := RenderServiceDocs(docs, "markdown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"| httpbin | httpbin.org | 443 | yes | - | - |",
+		"s3_access_key=****, s3_secret_key=****",
+		"| recv | 110 | My Dynamic Snippet One | yes | [vcl/dsnippet_my_dynamic_snippet_one.vcl](vcl/dsnippet_my_dynamic_snippet_one.vcl) |",
+		"| redirect_table | 3 | no | - |",
+		"- [main](vcl/main.vcl) (main)",
+	} {
+		if !strings.Contains(string(page), want) {
+			t.Errorf("docs do not contain %q:\n%s", want, page)
+		}
+	}
+	if strings.Contains(string(page), "s3_secret_key = ") {
+		t.Errorf("docs contain the secret:\n%s", page)
+	}
+}
+
+const mergedHCL = `resource "fastly_service_vcl" "service" {
+  name = "example"
+
+  acl {
+    name = "allow_list"
+  }
+  dictionary {
+    name = "config_table"
+  }
+  dictionary {
+    name = "redirect_table"
+  }
+  dynamicsnippet {
+    name     = "My Dynamic Snippet"
+    priority = 100
+    type     = "recv"
+  }
+}
+
+locals {
+  service_dictionary_items = {
+    "config_table" = {
+      "maintenance" = "true"
+    }
+    "redirect_table" = jsondecode(file("${path.module}/dictionaries/redirect_table.json"))
+  }
+}
+
+resource "fastly_service_dictionary_items" "service" {
+  dictionary_id = each.value.dictionary_id
+  items         = local.service_dictionary_items[each.key]
+  service_id    = fastly_service_vcl.service.id
+  for_each = {
+    for d in fastly_service_vcl.service.dictionary : d.name => d if contains(keys(local.service_dictionary_items), d.name)
+  }
+}
+
+locals {
+  service_acl_entries = {
+    "allow_list" = [
+      {
+        ip      = "192.0.2.0"
+        subnet  = 24
+        negated = false
+        comment = null
+      },
+      {
+        ip      = "198.51.100.1"
+        subnet  = null
+        negated = false
+        comment = null
+      },
+    ]
+  }
+}
+
+resource "fastly_service_acl_entries" "service" {
+  acl_id     = each.value.acl_id
+  service_id = fastly_service_vcl.service.id
+  for_each = {
+    for d in fastly_service_vcl.service.acl : d.name => d if contains(keys(local.service_acl_entries), d.name)
+  }
+
+  dynamic "entry" {
+    for_each = local.service_acl_entries[each.key]
+    content {
+      ip      = entry.value.ip
+      subnet  = entry.value.subnet
+      negated = entry.value.negated
+      comment = entry.value.comment
+    }
+  }
+}
+
+locals {
+  service_dynamic_snippet_content = {
+    "My Dynamic Snippet" = file("${path.module}/vcl/dsnippet_my_dynamic_snippet.vcl")
+  }
+}
+
+resource "fastly_service_dynamic_snippet_content" "service" {
+  snippet_id = each.value.snippet_id
+  content    = local.service_dynamic_snippet_content[each.key]
+  service_id = fastly_service_vcl.service.id
+  for_each = {
+    for d in fastly_service_vcl.service.dynamicsnippet : d.name => d if contains(keys(local.service_dynamic_snippet_content), d.name)
+  }
+}
+`
+
+func TestBuildServiceDocsMergedForEach(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "dictionaries"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "dictionaries", "redirect_table.json"), []byte(`{"a": "1", "b": "2"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tfconf, err := LoadTFConf(mergedHCL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs, err := tfconf.BuildServiceDocs(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 1 {
+		t.Fatalf("got %d services, want 1", len(docs))
+	}
+
+	doc := docs[0]
+	expected := []TableDoc{
+		{Name: "config_table", Size: 1},
+		{Name: "redirect_table", Size: 2, File: "dictionaries/redirect_table.json"},
+	}
+	if !reflect.DeepEqual(doc.Dictionaries, expected) {
+		t.Errorf("got dictionaries %+v, want %+v", doc.Dictionaries, expected)
+	}
+	if expected := []TableDoc{{Name: "allow_list", Size: 2}}; !reflect.DeepEqual(doc.ACLs, expected) {
+		t.Errorf("got ACLs %+v, want %+v", doc.ACLs, expected)
+	}
+	if len(doc.Snippets) != 1 || doc.Snippets[0].File != "vcl/dsnippet_my_dynamic_snippet.vcl" {
+		t.Errorf("got snippets %+v", doc.Snippets)
+	}
+}
+
+func TestBuildServiceDocsSameNames(t *testing.T) {
+	// Both services have a dictionary named geo
+	tfconf, err := LoadTFConf(`resource "fastly_service_vcl" "a" {
+  name = "a"
+
+  dictionary {
+    name = "geo"
+  }
+}
+
+resource "fastly_service_vcl" "b" {
+  name = "b"
+
+  dictionary {
+    name = "geo"
+  }
+}
+
+resource "fastly_service_dictionary_items" "a_geo" {
+  for_each = {
+    for d in fastly_service_vcl.a.dictionary : d.name => d if d.name == "geo"
+  }
+  items = {
+    "jp" = "1"
+  }
+}
+
+resource "fastly_service_dictionary_items" "b_geo" {
+  for_each = {
+    for d in fastly_service_vcl.b.dictionary : d.name => d if d.name == "geo"
+  }
+  items = {
+    "jp" = "1"
+    "us" = "2"
+  }
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs, err := tfconf.BuildServiceDocs(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 2 {
+		t.Fatalf("got %d services, want 2", len(docs))
+	}
+	for i, want := range []int{1, 2} {
+		if got := docs[i].Dictionaries[0].Size; got != want {
+			t.Errorf("%s: got %d items, want %d", docs[i].Address, got, want)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package terraformify
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+const (
+	IssueMissingIndexKey  = "missing-index-key"
+	IssueIndexKeyMismatch = "index-key-mismatch"
+	IssueNotActivated     = "not-activated"
+	IssueNotManaged       = "not-managed"
+)
+
+// StateIssue is an inconsistency between the configuration and the state that terraformify knows how to fix
+type StateIssue struct {
+	Kind    string
+	Address string
+	Message string
+
+	resourceType string
+	name         string
+	// index_key for the index key issues, or the attribute to set to true
+	value string
+	// The ID attribute and its value of the instance of a merged resource that the issue is about.
+	// The issues of the other resources are about all the instances.
+	idName string
+	id     string
+}
+
+func (i StateIssue) String() string {
+	return fmt.Sprintf("%s: %s: %s", i.Address, i.Kind, i.Message)
+}
+
+// DiagnoseState finds the inconsistencies between the configuration in dir and the state
+// that older versions of terraformify left behind
+func DiagnoseState(dir string, tfstate *TFState) ([]StateIssue, error) {
+	files, err := loadConfigFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	locals := localMaps(configBodies(files)...)
+
+	issues := make([]StateIssue, 0)
+	for _, f := range files {
+		for _, block := range f.Body().Blocks() {
+			labels := block.Labels()
+			if block.Type() != "resource" || len(labels) != 2 {
+				continue
+			}
+			r, err := tfstate.Resource(labels[0], labels[1])
+			if err != nil {
+				// Not imported yet
+				continue
+			}
+			issues = append(issues, diagnoseResource(block, r, tfstate, locals)...)
+		}
+	}
+	return issues, nil
+}
+
+func diagnoseResource(block *hclwrite.Block, r StateResource, tfstate *TFState, locals map[string]map[string]hclwrite.Tokens) []StateIssue {
+	var issues []StateIssue
+	body := block.Body()
+	issue := func(kind, value, format string, args ...interface{}) {
+		issues = append(issues, StateIssue{
+			Kind:         kind,
+			Address:      r.Address(),
+			Message:      fmt.Sprintf(format, args...),
+			resourceType: r.Type(),
+			name:         r.Name(),
+			value:        value,
+		})
+	}
+	indexKeyIssue := func(i StateInstance, key string) *StateIssue {
+		switch k := i.IndexKey().(type) {
+		case nil:
+			issue(IssueMissingIndexKey, key,
+				"the instance has no index_key, but for_each in the configuration has the key %s. Terraform plans to destroy it and create %s[%s]",
+				strconv.Quote(key), r.Address(), strconv.Quote(key))
+		case string:
+			if k == key {
+				return nil
+			}
+			issue(IssueIndexKeyMismatch, key,
+				"the instance has the index_key %s, but for_each in the configuration has the key %s. Terraform plans to replace it",
+				strconv.Quote(k), strconv.Quote(key))
+		default:
+			return nil
+		}
+		return &issues[len(issues)-1]
+	}
+
+	instances := r.Instances()
+	if s, ok := parseForEach(block, locals); ok {
+		names := s.names()
+		if len(names) == 1 && s.blocks[names[0]] == block {
+			if len(instances) == 1 {
+				indexKeyIssue(instances[0], names[0])
+			}
+		} else {
+			// The instances of a merged resource are told apart by the IDs of the nested blocks they belong to
+			for _, i := range instances {
+				name, idName, id, err := mergedInstanceName(i, s, tfstate)
+				if err != nil {
+					log.Printf("[WARN] %v", err)
+					continue
+				}
+				if _, ok := s.blocks[name]; !ok {
+					continue
+				}
+				if is := indexKeyIssue(i, name); is != nil {
+					is.Address = i.Address()
+					is.idName, is.id = idName, id
+				}
+			}
+		}
+	}
+
+	if activateResourceTypes[r.Type()] && !attributeIsFalse(body, "activate") {
+		for _, i := range instances {
+			if v, _ := i.Attributes().Value("activate"); v != true {
+				issue(IssueNotActivated, "activate",
+					"activate is not true in the state. Terraform plans an update that changes nothing but activate")
+				break
+			}
+		}
+	}
+
+	if attr, ok := manageAttrs[r.Type()]; ok && attributeIsTrue(body, attr) {
+		for _, i := range instances {
+			if v, _ := i.Attributes().Value(attr); v != true {
+				issue(IssueNotManaged, attr,
+					"%s is true in the configuration but not in the state. Terraform plans an update that changes nothing but %s", attr, attr)
+				break
+			}
+		}
+	}
+	return issues
+}
+
+// mergedInstanceName returns the name of the nested block of the service that the instance of a merged resource belongs to,
+// along with the ID attribute, e.g. dictionary_id, and its value that identify the nested block
+func mergedInstanceName(i StateInstance, s forEachSelection, tfstate *TFState) (string, string, string, error) {
+	r, ok := mergeableResources[i.resource.Type()]
+	if !ok {
+		return "", "", "", fmt.Errorf("%s is not a resource that can be merged", i.Address())
+	}
+	id, err := i.Attributes().String(r.idName)
+	if err != nil {
+		return "", "", "", err
+	}
+	service := strings.SplitN(s.service, ".", 2)
+	attrs, err := tfstate.Attributes(service[0], service[1])
+	if err != nil {
+		return "", "", "", err
+	}
+	nested, err := attrs.NestedBlockBy(s.blockType, r.idName, id)
+	if err != nil {
+		return "", "", "", err
+	}
+	name, err := nested.String("name")
+	return name, r.idName, id, err
+}
+
+func attributeIsTrue(body *hclwrite.Body, name string) bool {
+	attr := body.GetAttribute(name)
+	return attr != nil && strings.TrimSpace(string(attr.Expr().BuildTokens(nil).Bytes())) == "true"
+}
+
+func attributeIsFalse(body *hclwrite.Body, name string) bool {
+	attr := body.GetAttribute(name)
+	return attr != nil && strings.TrimSpace(string(attr.Expr().BuildTokens(nil).Bytes())) == "false"
+}
+
+// RepairState returns a new state with the issues fixed
+func (s *TFState) RepairState(issues []StateIssue) (*TFState, error) {
+	newState, err := s.Clone()
+	if err != nil {
+		return nil, err
+	}
+	for _, issue := range issues {
+		r, err := newState.Resource(issue.resourceType, issue.name)
+		if err != nil {
+			return nil, err
+		}
+		for _, i := range r.Instances() {
+			if issue.idName != "" {
+				if id, _ := i.Attributes().String(issue.idName); id != issue.id {
+					continue
+				}
+			}
+			switch issue.Kind {
+			case IssueMissingIndexKey, IssueIndexKeyMismatch:
+				i.SetIndexKey(issue.value)
+			case IssueNotActivated, IssueNotManaged:
+				i.Attributes().Set(issue.value, true)
+			default:
+				return nil, fmt.Errorf("unknown issue %q", issue.Kind)
+			}
+		}
+	}
+	return newState, nil
+}
+
+// BackupTFState writes the state next to terraform.tfstate, named as "terraform state" commands name their backups
+func BackupTFState(workingDir string, s *TFState) (string, error) {
+	path := filepath.Join(workingDir, fmt.Sprintf("terraform.tfstate.%d.backup", time.Now().Unix()))
+	if err := os.WriteFile(path, s.Bytes(), 0644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
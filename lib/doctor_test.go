@@ -0,0 +1,124 @@
+package terraformify
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiagnoseState(t *testing.T) {
+	testCases := []struct {
+		name     string
+		config   string
+		state    string
+		expected map[string]int
+	}{
+		{
+			// The state right after "terraform import"
+			name:  "imported",
+			state: "../testdata/terraform.tfstate",
+			expected: map[string]int{
+				IssueMissingIndexKey: 6,
+				IssueNotActivated:    2,
+			},
+		},
+		{
+			name: "managed",
+			config: `resource "fastly_service_dictionary_items" "geo" {
+  manage_items = true
+  for_each = {
+    for d in fastly_service_vcl.service.dictionary : d.name => d if d.name == "geo"
+  }
+}
+`,
+			state: `{"resources": [{"mode": "managed", "type": "fastly_service_dictionary_items", "name": "geo",
+				"instances": [{"index_key": "old_geo", "attributes": {"manage_items": false}}]}]}`,
+			expected: map[string]int{
+				IssueIndexKeyMismatch: 1,
+				IssueNotManaged:       1,
+			},
+		},
+		{
+			// The instances of a merged resource are keyed by the names of the dictionaries they belong to
+			name: "merged",
+			config: `locals {
+  service_dictionary_items = {
+    "config_table"   = {}
+    "geo"            = {}
+    "redirect_table" = {}
+  }
+}
+
+resource "fastly_service_dictionary_items" "service" {
+  for_each = {
+    for d in fastly_service_vcl.service.dictionary : d.name => d if contains(keys(local.service_dictionary_items), d.name)
+  }
+}
+`,
+			state: `{"resources": [
+				{"mode": "managed", "type": "fastly_service_vcl", "name": "service", "instances": [{"attributes": {"dictionary": [
+					{"name": "config_table", "dictionary_id": "d1"},
+					{"name": "redirect_table", "dictionary_id": "d2"},
+					{"name": "geo", "dictionary_id": "d3"}]}}]},
+				{"mode": "managed", "type": "fastly_service_dictionary_items", "name": "service", "instances": [
+					{"index_key": "config_table", "attributes": {"dictionary_id": "d1"}},
+					{"attributes": {"dictionary_id": "d2"}},
+					{"index_key": "config_table", "attributes": {"dictionary_id": "d3"}}]}]}`,
+			expected: map[string]int{
+				IssueMissingIndexKey:  1,
+				IssueIndexKeyMismatch: 1,
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		dir := t.TempDir()
+		config := []byte(tt.config)
+		tfstate := &TFState{}
+		if tt.config == "" {
+			b, err := os.ReadFile(goldenFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			config = b
+			tfstate, err = loadTFStateFile(tt.state)
+			if err != nil {
+				t.Fatal(err)
+			}
+		} else {
+			tfstate = parseTestState(t, tt.state)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "main.tf"), config, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		issues, err := DiagnoseState(dir, tfstate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts := make(map[string]int)
+		for _, issue := range issues {
+			counts[issue.Kind]++
+		}
+		for kind, n := range tt.expected {
+			if counts[kind] != n {
+				t.Errorf("%s: got %d %s issues, want %d: %v", tt.name, counts[kind], kind, n, issues)
+			}
+		}
+		if len(issues) == 0 {
+			continue
+		}
+
+		fixed, err := tfstate.RepairState(issues)
+		if err != nil {
+			t.Fatal(err)
+		}
+		issues, err = DiagnoseState(dir, fixed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(issues) != 0 {
+			t.Errorf("%s: issues are left after the repair: %v", tt.name, issues)
+		}
+	}
+}
@@ -0,0 +1,365 @@
+package terraformify
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-exec/tfexec"
+)
+
+// Drift is a change made outside of Terraform to a resource or a nested block of it
+type Drift struct {
+	Address string `json:"address"`
+	Block   string `json:"block,omitempty"`
+	Message string `json:"message"`
+}
+
+func (d Drift) String() string {
+	if d.Block == "" {
+		return fmt.Sprintf("%s: %s", d.Address, d.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", d.Address, d.Block, d.Message)
+}
+
+// DriftReport is the result of a drift check of the working directory
+type DriftReport struct {
+	CheckedAt time.Time `json:"checked_at"`
+	Resources []string  `json:"resources"`
+	Drifts    []Drift   `json:"drifts"`
+}
+
+// planJSON is the part of "terraform show -json <plan>" output used to detect drift
+type planJSON struct {
+	PriorState struct {
+		Values struct {
+			RootModule struct {
+				Resources []struct {
+					Address string `json:"address"`
+					Mode    string `json:"mode"`
+				} `json:"resources"`
+			} `json:"root_module"`
+		} `json:"values"`
+	} `json:"prior_state"`
+	ResourceDrift []struct {
+		Address string `json:"address"`
+		Mode    string `json:"mode"`
+		Change  struct {
+			Actions []string               `json:"actions"`
+			Before  map[string]interface{} `json:"before"`
+			After   map[string]interface{} `json:"after"`
+		} `json:"change"`
+	} `json:"resource_drift"`
+}
+
+// CheckDrift runs a refresh-only plan in the working directory and reports the changes made outside of Terraform.
+// The state is left untouched.
+func CheckDrift(tf *tfexec.Terraform) (*DriftReport, error) {
+	tempDir, err := os.MkdirTemp("", "terraformify")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tempDir)
+
+	planFile := filepath.Join(tempDir, "drift.tfplan")
+	_, err = terraformExec(tf, "plan", "-refresh-only", "-input=false", "-lock=false", "-no-color", "-out="+planFile)
+	if err != nil {
+		return nil, err
+	}
+	out, err := terraformExec(tf, "show", "-json", planFile)
+	if err != nil {
+		return nil, err
+	}
+	return BuildDriftReport(out, time.Now())
+}
+
+// BuildDriftReport builds the report from the JSON representation of the refresh-only plan
+func BuildDriftReport(plan []byte, checkedAt time.Time) (*DriftReport, error) {
+	var p planJSON
+	if err := json.Unmarshal(plan, &p); err != nil {
+		return nil, fmt.Errorf("drift: invalid plan json: %w", err)
+	}
+
+	report := &DriftReport{
+		CheckedAt: checkedAt,
+		Resources: make([]string, 0),
+		Drifts:    make([]Drift, 0),
+	}
+	// prior_state is the state after the refresh, which no longer has the resources deleted outside of Terraform.
+	// They are only in resource_drift.
+	seen := make(map[string]bool)
+	addResource := func(mode, addr string) {
+		if mode == "managed" && !seen[addr] {
+			seen[addr] = true
+			report.Resources = append(report.Resources, addr)
+		}
+	}
+	for _, r := range p.PriorState.Values.RootModule.Resources {
+		addResource(r.Mode, r.Address)
+	}
+	for _, r := range p.ResourceDrift {
+		addResource(r.Mode, r.Address)
+	}
+	sort.Strings(report.Resources)
+
+	for _, r := range p.ResourceDrift {
+		if r.Change.After == nil || containsString(r.Change.Actions, "delete") {
+			report.Drifts = append(report.Drifts, Drift{Address: r.Address, Message: "deleted"})
+			continue
+		}
+		report.Drifts = append(report.Drifts, diffObjects(r.Address, "", r.Change.Before, r.Change.After)...)
+	}
+	sort.SliceStable(report.Drifts, func(i, j int) bool {
+		return report.Drifts[i].Address < report.Drifts[j].Address
+	})
+	return report, nil
+}
+
+// diffObjects reports the changed attributes of the objects.
+// Lists of objects are the nested blocks, which are matched by name.
+func diffObjects(addr, block string, before, after map[string]interface{}) []Drift {
+	keys := make([]string, 0, len(before)+len(after))
+	for k := range before {
+		keys = append(keys, k)
+	}
+	for k := range after {
+		if _, ok := before[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	drifts := make([]Drift, 0)
+	for _, k := range keys {
+		b, a := before[k], after[k]
+		if reflect.DeepEqual(b, a) {
+			continue
+		}
+		bBlocks, bOK := nestedObjects(b)
+		aBlocks, aOK := nestedObjects(a)
+		if block == "" && bOK && aOK {
+			drifts = append(drifts, diffNestedObjects(addr, k, bBlocks, aBlocks)...)
+			continue
+		}
+		drifts = append(drifts, Drift{Address: addr, Block: block, Message: k + " changed"})
+	}
+	return drifts
+}
+
+func diffNestedObjects(addr, blockType string, before, after []map[string]interface{}) []Drift {
+	bKeyed := keyNestedObjects(blockType, before)
+	aKeyed := keyNestedObjects(blockType, after)
+
+	keys := make([]string, 0, len(bKeyed)+len(aKeyed))
+	for k := range bKeyed {
+		keys = append(keys, k)
+	}
+	for k := range aKeyed {
+		if _, ok := bKeyed[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	drifts := make([]Drift, 0)
+	for _, k := range keys {
+		b, inBefore := bKeyed[k]
+		a, inAfter := aKeyed[k]
+		switch {
+		case !inBefore:
+			drifts = append(drifts, Drift{Address: addr, Block: k, Message: "added"})
+		case !inAfter:
+			drifts = append(drifts, Drift{Address: addr, Block: k, Message: "removed"})
+		default:
+			drifts = append(drifts, diffObjects(addr, k, b, a)...)
+		}
+	}
+	return drifts
+}
+
+// keyNestedObjects keys the nested blocks by the block type and the name, e.g. backend "origin".
+// Blocks without a name are keyed by the position.
+func keyNestedObjects(blockType string, objects []map[string]interface{}) map[string]map[string]interface{} {
+	keyed := make(map[string]map[string]interface{}, len(objects))
+	for i, o := range objects {
+		key := fmt.Sprintf("%s[%d]", blockType, i)
+		if name, ok := o["name"].(string); ok {
+			key = fmt.Sprintf("%s %s", blockType, strconv.Quote(name))
+		}
+		keyed[key] = o
+	}
+	return keyed
+}
+
+// nestedObjects returns the value as a list of objects if it represents nested blocks
+func nestedObjects(v interface{}) ([]map[string]interface{}, bool) {
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, false
+	}
+	objects := make([]map[string]interface{}, 0, len(list))
+	for _, e := range list {
+		o, ok := e.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		objects = append(objects, o)
+	}
+	return objects, true
+}
+
+func containsString(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+// drifts groups the drifts by resource address
+func (r *DriftReport) drifts() map[string][]Drift {
+	m := make(map[string][]Drift)
+	for _, d := range r.Drifts {
+		m[d.Address] = append(m[d.Address], d)
+	}
+	return m
+}
+
+// ValidateDriftReportFormat returns an error if the report cannot be rendered in the format
+func ValidateDriftReportFormat(format string) error {
+	switch format {
+	case "text", "json", "junit", "markdown":
+		return nil
+	default:
+		return fmt.Errorf("unknown format %q: must be one of text, json, junit, markdown", format)
+	}
+}
+
+// Format renders the report in the format: text, json, junit or markdown
+func (r *DriftReport) Format(format string) ([]byte, error) {
+	switch format {
+	case "text":
+		return r.text(), nil
+	case "json":
+		b, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(b, '\n'), nil
+	case "junit":
+		return r.junit()
+	case "markdown":
+		return r.markdown(), nil
+	default:
+		return nil, ValidateDriftReportFormat(format)
+	}
+}
+
+func (r *DriftReport) text() []byte {
+	var buf bytes.Buffer
+	if len(r.Drifts) == 0 {
+		fmt.Fprintf(&buf, "No drift detected in %d resources\n", len(r.Resources))
+		return buf.Bytes()
+	}
+	for _, d := range r.Drifts {
+		fmt.Fprintln(&buf, d)
+	}
+	return buf.Bytes()
+}
+
+func (r *DriftReport) markdown() []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "## Drift report\n\n")
+	fmt.Fprintf(&buf, "Checked at %s.\n\n", r.CheckedAt.UTC().Format(time.RFC3339))
+	if len(r.Drifts) == 0 {
+		fmt.Fprintf(&buf, "No drift detected in %d resources.\n", len(r.Resources))
+		return buf.Bytes()
+	}
+
+	fmt.Fprintf(&buf, "%d changes made outside of Terraform.\n\n", len(r.Drifts))
+	fmt.Fprintf(&buf, "| Resource | Block | Change |\n")
+	fmt.Fprintf(&buf, "| --- | --- | --- |\n")
+	for _, d := range r.Drifts {
+		fmt.Fprintf(&buf, "| `%s` | %s | %s |\n", d.Address, markdownEscape(d.Block), markdownEscape(d.Message))
+	}
+	return buf.Bytes()
+}
+
+func markdownEscape(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// junit renders the report as JUnit XML, with a test case for each resource
+func (r *DriftReport) junit() ([]byte, error) {
+	suite := junitTestSuite{
+		Name:      "drift",
+		Timestamp: r.CheckedAt.UTC().Format(time.RFC3339),
+	}
+	drifts := r.drifts()
+
+	// Resources deleted outside of Terraform are in Resources as well, taken from resource_drift
+	for _, addr := range r.Resources {
+		tc := junitTestCase{Name: addr, ClassName: "terraformify.drift"}
+		if ds, ok := drifts[addr]; ok {
+			lines := make([]string, 0, len(ds))
+			for _, d := range ds {
+				lines = append(lines, d.String())
+			}
+			tc.Failure = &junitFailure{
+				Message: fmt.Sprintf("%d changes made outside of Terraform", len(ds)),
+				Text:    strings.Join(lines, "\n"),
+			}
+			suite.Failures++
+		}
+		suite.Cases = append(suite.Cases, tc)
+		suite.Tests++
+	}
+
+	suites := junitTestSuites{
+		Name:     "terraformify",
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Suites:   []junitTestSuite{suite},
+	}
+	b, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte(xml.Header), b...), '\n'), nil
+}
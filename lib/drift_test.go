@@ -0,0 +1,119 @@
+package terraformify
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const driftPlan = `{
+  "prior_state": {
+    "values": {
+      "root_module": {
+        "resources": [
+          {"address": "fastly_service_vcl.service", "mode": "managed"},
+          {"address": "fastly_service_dictionary_items.dict[\"dict\"]", "mode": "managed"}
+        ]
+      }
+    }
+  },
+  "resource_drift": [
+    {
+      "address": "fastly_service_vcl.service",
+      "change": {
+        "actions": ["update"],
+        "before": {
+          "comment": "",
+          "backend": [
+            {"name": "origin", "address": "origin1.example.com", "port": 443},
+            {"name": "old", "address": "old.example.com", "port": 443}
+          ]
+        },
+        "after": {
+          "comment": "changed in the UI",
+          "backend": [
+            {"name": "origin", "address": "origin2.example.com", "port": 443},
+            {"name": "new", "address": "new.example.com", "port": 443}
+          ]
+        }
+      }
+    }
+  ]
+}`
+
+func TestBuildDriftReport(t *testing.T) {
+	report, err := BuildDriftReport([]byte(driftPlan), time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make([]string, 0)
+	for _, d := range report.Drifts {
+		got = append(got, d.String())
+	}
+	want := []string{
+		`fastly_service_vcl.service: backend "new": added`,
+		`fastly_service_vcl.service: backend "old": removed`,
+		`fastly_service_vcl.service: backend "origin": address changed`,
+		`fastly_service_vcl.service: comment changed`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	junit, err := report.Format("junit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(junit), `<testsuite name="drift" tests="2" failures="1"`) {
+		t.Errorf("unexpected junit report:\n%s", junit)
+	}
+}
+
+func TestBuildDriftReportDeleted(t *testing.T) {
+	// The dictionary items deleted in the UI are no longer in prior_state, which is the state after the refresh
+	plan := `{
+  "prior_state": {
+    "values": {
+      "root_module": {
+        "resources": [
+          {"address": "fastly_service_vcl.service", "mode": "managed"}
+        ]
+      }
+    }
+  },
+  "resource_drift": [
+    {
+      "address": "fastly_service_dictionary_items.dict[\"dict\"]",
+      "mode": "managed",
+      "change": {
+        "actions": ["delete"],
+        "before": {"items": {"a": "1"}},
+        "after": null
+      }
+    }
+  ]
+}`
+	report, err := BuildDriftReport([]byte(plan), time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{`fastly_service_dictionary_items.dict["dict"]`, "fastly_service_vcl.service"}
+	if !reflect.DeepEqual(report.Resources, want) {
+		t.Errorf("got %q, want %q", report.Resources, want)
+	}
+
+	junit, err := report.Format("junit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		`<testsuite name="drift" tests="2" failures="1"`,
+		`<testcase name="fastly_service_dictionary_items.dict[&#34;dict&#34;]" classname="terraformify.drift">`,
+	} {
+		if !strings.Contains(string(junit), s) {
+			t.Errorf("%s is not found in the junit report:\n%s", s, junit)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package terraformify
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const fastlyAPIEndpoint = "https://api.fastly.com"
+
+var (
+	ErrAPINotFound = errors.New("not found")
+)
+
+// FastlyClient is a minimal Fastly API client.
+// It is used to discover resources that cannot be enumerated through the Terraform provider.
+type FastlyClient struct {
+	Endpoint   string
+	APIKey     string
+	HTTPClient *http.Client
+}
+
+func NewFastlyClient(apiKey string) *FastlyClient {
+	endpoint := os.Getenv("FASTLY_API_URL")
+	if endpoint == "" {
+		endpoint = fastlyAPIEndpoint
+	}
+	return &FastlyClient{
+		Endpoint:   strings.TrimSuffix(endpoint, "/"),
+		APIKey:     apiKey,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// jsonAPIResource is a resource object in JSON:API responses
+type jsonAPIResource struct {
+	ID            string                         `json:"id"`
+	Type          string                         `json:"type"`
+	Attributes    map[string]interface{}         `json:"attributes"`
+	Relationships map[string]jsonAPIRelationship `json:"relationships"`
+}
+
+type jsonAPIRelationship struct {
+	Data json.RawMessage `json:"data"`
+}
+
+// IDs returns the IDs of the related resources
+func (r jsonAPIRelationship) IDs() []string {
+	var one struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(r.Data, &one); err == nil && one.ID != "" {
+		return []string{one.ID}
+	}
+
+	var many []struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(r.Data, &many); err != nil {
+		return nil
+	}
+	ids := make([]string, 0, len(many))
+	for _, d := range many {
+		ids = append(ids, d.ID)
+	}
+	return ids
+}
+
+func (r jsonAPIResource) stringAttribute(key string) string {
+	s, _ := r.Attributes[key].(string)
+	return s
+}
+
+func (c *FastlyClient) get(path string, query url.Values, v interface{}) error {
+	u := c.Endpoint + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	return c.getURL(u, v)
+}
+
+func (c *FastlyClient) getURL(u string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Fastly-Key", c.APIKey)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("fastly: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("fastly: GET %s: %w", req.URL.Path, ErrAPINotFound)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("fastly: GET %s: %s: %s", req.URL.Path, resp.Status, body)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("fastly: GET %s: invalid json: %w", req.URL.Path, err)
+	}
+	return nil
+}
+
+// listJSONAPI returns all resources of the JSON:API collection following the pagination links
+func (c *FastlyClient) listJSONAPI(path string, query url.Values) ([]jsonAPIResource, error) {
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set("page[size]", "100")
+
+	var resources []jsonAPIResource
+	u := c.Endpoint + path + "?" + query.Encode()
+	for u != "" {
+		var page struct {
+			Data  []jsonAPIResource `json:"data"`
+			Links struct {
+				Next string `json:"next"`
+			} `json:"links"`
+		}
+		if err := c.getURL(u, &page); err != nil {
+			return nil, err
+		}
+		resources = append(resources, page.Data...)
+		u = page.Links.Next
+	}
+	return resources, nil
+}
+
+// GetServiceName returns the name of the service
+func (c *FastlyClient) GetServiceName(serviceID string) (string, error) {
+	var service struct {
+		Name string `json:"name"`
+	}
+	if err := c.get("/service/"+url.PathEscape(serviceID), nil, &service); err != nil {
+		return "", err
+	}
+	return service.Name, nil
+}
+
+// GetLatestVersion returns the latest version number of the service
+func (c *FastlyClient) GetLatestVersion(serviceID string) (int, error) {
+	var versions []struct {
+		Number int `json:"number"`
+	}
+	if err := c.get("/service/"+url.PathEscape(serviceID)+"/version", nil, &versions); err != nil {
+		return 0, err
+	}
+
+	latest := 0
+	for _, v := range versions {
+		if v.Number > latest {
+			latest = v.Number
+		}
+	}
+	if latest == 0 {
+		return 0, fmt.Errorf("fastly: service %s has no versions", serviceID)
+	}
+	return latest, nil
+}
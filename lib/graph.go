@@ -0,0 +1,248 @@
+package terraformify
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Attributes of the nested blocks that refer to other nested blocks by name, and the types of the referred blocks
+var nameReferenceAttrs = map[string]string{
+	"request_condition":  "condition",
+	"response_condition": "condition",
+	"cache_condition":    "condition",
+	"prefetch_condition": "condition",
+	"healthcheck":        "healthcheck",
+}
+
+// Types of the nested blocks that are only useful when other blocks refer to them
+var referredBlockTypes = map[string]bool{
+	"condition":   true,
+	"healthcheck": true,
+}
+
+// GraphNode is a resource or a nested block of a service
+type GraphNode struct {
+	ID      string
+	Address string
+	// Unused is set for the blocks that exist only to be referred to, but no block refers to
+	Unused bool
+	// Missing is set for the names referred to that no block has
+	Missing bool
+}
+
+// GraphEdge is a reference from a block to another
+type GraphEdge struct {
+	From  *GraphNode
+	To    *GraphNode
+	Label string
+}
+
+// ConfigGraph is the graph of the references between the blocks in the configuration
+type ConfigGraph struct {
+	Nodes []*GraphNode
+	Edges []GraphEdge
+	nodes map[string]*GraphNode
+}
+
+func (g *ConfigGraph) node(addr string) *GraphNode {
+	if n, ok := g.nodes[addr]; ok {
+		return n
+	}
+	n := &GraphNode{ID: fmt.Sprintf("n%d", len(g.Nodes)), Address: addr}
+	g.nodes[addr] = n
+	g.Nodes = append(g.Nodes, n)
+	return n
+}
+
+// BuildGraph builds the graph of the name references between the nested blocks of the services,
+// and the links from the associated resources to the services.
+func (tfconf *TFConf) BuildGraph() *ConfigGraph {
+	g := &ConfigGraph{nodes: make(map[string]*GraphNode)}
+
+	resources := resourceBlocks(tfconf)
+	addrs := make([]string, 0, len(resources))
+	for addr := range resources {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+
+	// Services and their nested blocks
+	for _, addr := range addrs {
+		block := resources[addr]
+		if !isServiceType(block.Labels()[0]) {
+			continue
+		}
+		service := g.node(addr)
+
+		nested := nestedBlocks(block.Body())
+		keys := make([]string, 0, len(nested))
+		for key := range nested {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		referred := make(map[string]bool)
+		for _, key := range keys {
+			from := g.node(addr + "." + key)
+			g.Edges = append(g.Edges, GraphEdge{From: service, To: from})
+
+			attrs := nested[key].Body().Attributes()
+			names := make([]string, 0, len(attrs))
+			for name := range attrs {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, attrName := range names {
+				targetType, ok := nameReferenceAttrs[attrName]
+				if !ok {
+					continue
+				}
+				name := stringAttr(nested[key], attrName)
+				if name == "" {
+					continue
+				}
+				targetKey := fmt.Sprintf("%s[%s]", targetType, strconv.Quote(name))
+				to := g.node(addr + "." + targetKey)
+				if _, ok := nested[targetKey]; !ok {
+					to.Missing = true
+				}
+				referred[targetKey] = true
+				g.Edges = append(g.Edges, GraphEdge{From: from, To: to, Label: attrName})
+			}
+		}
+
+		for _, key := range keys {
+			if referredBlockTypes[nested[key].Type()] && !referred[key] {
+				g.node(addr + "." + key).Unused = true
+			}
+		}
+	}
+
+	// Associated resources linked to the services
+	locals := localMaps(tfconf.Body())
+	for _, addr := range addrs {
+		block := resources[addr]
+		if isServiceType(block.Labels()[0]) {
+			continue
+		}
+		from := g.node(addr)
+		body := block.Body()
+
+		// for_each picks the nested blocks of the service by name
+		if s, ok := parseForEach(block, locals); ok {
+			for _, name := range s.names() {
+				target := s.target(name)
+				_, exists := g.nodes[target]
+				to := g.node(target)
+				if !exists {
+					to.Missing = true
+				}
+				g.Edges = append(g.Edges, GraphEdge{From: from, To: to, Label: "for_each"})
+			}
+			continue
+		}
+		for _, attrName := range []string{"service_id", "waf_id"} {
+			attr := body.GetAttribute(attrName)
+			if attr == nil {
+				continue
+			}
+			expr := strings.TrimSpace(string(attr.Expr().BuildTokens(nil).Bytes()))
+			if target, ok := referenceTarget(expr); ok {
+				g.Edges = append(g.Edges, GraphEdge{From: from, To: g.node(target), Label: attrName})
+			}
+		}
+	}
+
+	return g
+}
+
+func isServiceType(resourceType string) bool {
+	return resourceType == "fastly_service_vcl" || resourceType == "fastly_service_compute"
+}
+
+// referenceTarget returns the address of the resource, or the nested block, that the reference expression points to
+func referenceTarget(expr string) (string, bool) {
+	// e.g. fastly_service_vcl.service.waf[0].waf_id
+	parts := strings.Split(expr, ".")
+	if len(parts) < 3 {
+		return "", false
+	}
+	addr := parts[0] + "." + parts[1]
+	if len(parts) == 4 && strings.HasSuffix(parts[2], "[0]") {
+		return addr + "." + parts[2], true
+	}
+	return addr, true
+}
+
+// Issues returns the dangling references and the unused blocks
+func (g *ConfigGraph) Issues() []string {
+	issues := make([]string, 0)
+	for _, n := range g.Nodes {
+		switch {
+		case n.Missing:
+			issues = append(issues, fmt.Sprintf("%s is referred to but does not exist", n.Address))
+		case n.Unused:
+			issues = append(issues, fmt.Sprintf("%s is not used by any block", n.Address))
+		}
+	}
+	sort.Strings(issues)
+	return issues
+}
+
+// Dot renders the graph in the Graphviz DOT language
+func (g *ConfigGraph) Dot() []byte {
+	var buf bytes.Buffer
+	buf.WriteString("digraph terraformify {\n")
+	buf.WriteString("  rankdir = \"LR\";\n")
+	buf.WriteString("  node [shape = \"box\"];\n")
+	for _, n := range g.Nodes {
+		attrs := fmt.Sprintf("label = %s", strconv.Quote(n.Address))
+		switch {
+		case n.Missing:
+			attrs += `, color = "red", style = "dashed"`
+		case n.Unused:
+			attrs += `, color = "orange", style = "dashed"`
+		}
+		fmt.Fprintf(&buf, "  %s [%s];\n", n.ID, attrs)
+	}
+	for _, e := range g.Edges {
+		if e.Label == "" {
+			fmt.Fprintf(&buf, "  %s -> %s;\n", e.From.ID, e.To.ID)
+			continue
+		}
+		fmt.Fprintf(&buf, "  %s -> %s [label = %s];\n", e.From.ID, e.To.ID, strconv.Quote(e.Label))
+	}
+	buf.WriteString("}\n")
+	return buf.Bytes()
+}
+
+// Mermaid renders the graph as a Mermaid flowchart
+func (g *ConfigGraph) Mermaid() []byte {
+	var buf bytes.Buffer
+	buf.WriteString("flowchart LR\n")
+	for _, n := range g.Nodes {
+		// Mermaid labels cannot contain double quotes
+		fmt.Fprintf(&buf, "  %s[\"%s\"]\n", n.ID, strings.ReplaceAll(n.Address, `"`, "#quot;"))
+	}
+	for _, e := range g.Edges {
+		if e.Label == "" {
+			fmt.Fprintf(&buf, "  %s --> %s\n", e.From.ID, e.To.ID)
+			continue
+		}
+		fmt.Fprintf(&buf, "  %s -- %s --> %s\n", e.From.ID, e.Label, e.To.ID)
+	}
+	buf.WriteString("  classDef missing stroke:#f00,stroke-dasharray:5 5\n")
+	buf.WriteString("  classDef unused stroke:#f90,stroke-dasharray:5 5\n")
+	for _, n := range g.Nodes {
+		switch {
+		case n.Missing:
+			fmt.Fprintf(&buf, "  class %s missing\n", n.ID)
+		case n.Unused:
+			fmt.Fprintf(&buf, "  class %s unused\n", n.ID)
+		}
+	}
+	return buf.Bytes()
+}
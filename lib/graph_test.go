@@ -0,0 +1,118 @@
+package terraformify
+
+import (
+	"reflect"
+	"testing"
+)
+
+const graphHCL = `resource "fastly_service_vcl" "service" {
+  name = "example"
+
+  backend {
+    address           = "origin.example.com"
+    healthcheck       = "missing check"
+    name              = "origin"
+    request_condition = "is_api"
+  }
+  condition {
+    name      = "is_api"
+    statement = "req.url ~ \"^/api\""
+    type      = "REQUEST"
+  }
+  condition {
+    name      = "unused"
+    statement = "true"
+    type      = "REQUEST"
+  }
+  dictionary {
+    name = "dict"
+  }
+}
+
+resource "fastly_service_dictionary_items" "dict" {
+  for_each = {
+    for d in fastly_service_vcl.service.dictionary : d.name => d if d.name == "dict"
+  }
+  service_id    = fastly_service_vcl.service.id
+  dictionary_id = each.value.dictionary_id
+}
+`
+
+func TestBuildGraph(t *testing.T) {
+	tfconf, err := LoadTFConf(graphHCL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	graph := tfconf.BuildGraph()
+
+	want := []string{
+		`fastly_service_vcl.service.condition["unused"] is not used by any block`,
+		`fastly_service_vcl.service.healthcheck["missing check"] is referred to but does not exist`,
+	}
+	if got := graph.Issues(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	edges := make(map[string]bool)
+	for _, e := range graph.Edges {
+		edges[e.From.Address+" -> "+e.To.Address] = true
+	}
+	for _, edge := range []string{
+		`fastly_service_vcl.service.backend["origin"] -> fastly_service_vcl.service.condition["is_api"]`,
+		`fastly_service_dictionary_items.dict -> fastly_service_vcl.service.dictionary["dict"]`,
+	} {
+		if !edges[edge] {
+			t.Errorf("edge %s is not found", edge)
+		}
+	}
+}
+
+func TestBuildGraphMergedForEach(t *testing.T) {
+	tfconf, err := LoadTFConf(`resource "fastly_service_vcl" "service" {
+  name = "example"
+
+  dictionary {
+    name = "config_table"
+  }
+}
+
+locals {
+  service_dictionary_items = {
+    "config_table" = {}
+    "gone"         = {}
+  }
+}
+
+resource "fastly_service_dictionary_items" "service" {
+  for_each = {
+    for d in fastly_service_vcl.service.dictionary : d.name => d if contains(keys(local.service_dictionary_items), d.name)
+  }
+  dictionary_id = each.value.dictionary_id
+  items         = local.service_dictionary_items[each.key]
+  service_id    = fastly_service_vcl.service.id
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	graph := tfconf.BuildGraph()
+
+	want := []string{
+		`fastly_service_vcl.service.dictionary["gone"] is referred to but does not exist`,
+	}
+	if got := graph.Issues(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	edges := make(map[string]string)
+	for _, e := range graph.Edges {
+		edges[e.From.Address+" -> "+e.To.Address] = e.Label
+	}
+	for _, edge := range []string{
+		`fastly_service_dictionary_items.service -> fastly_service_vcl.service.dictionary["config_table"]`,
+		`fastly_service_dictionary_items.service -> fastly_service_vcl.service.dictionary["gone"]`,
+	} {
+		if edges[edge] != "for_each" {
+			t.Errorf("edge %s is not found", edge)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package terraformify
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// FileVars is the data passed to the layout templates
+type FileVars struct {
+	// Type of the block the file is extracted from, e.g. "vcl", "snippet", "response_object" and "logging_s3"
+	Type string
+	// Type and priority of the snippet, for snippets and dynamic snippets
+	SnippetType string
+	Priority    int
+	// Normalized name of the block
+	Name string
+	// Extension of the file without the leading dot, e.g. "vcl" and "json.tftpl"
+	Ext string
+}
+
+// DefaultFileLayouts are the templates of the paths of the extracted files, relative to FileDir, keyed by kind
+var DefaultFileLayouts = map[string]string{
+	"vcl":            "vcl/{{.Name}}.{{.Ext}}",
+	"snippet":        "vcl/snippet_{{.Name}}.{{.Ext}}",
+	"dynamicsnippet": "vcl/dsnippet_{{.Name}}.{{.Ext}}",
+	"content":        "content/{{.Name}}.{{.Ext}}",
+	"logformat":      "logformat/{{.Name}}.{{.Ext}}",
+}
+
+// FileLayoutKinds returns the kinds of the files whose layout can be configured
+func FileLayoutKinds() []string {
+	kinds := make([]string, 0, len(DefaultFileLayouts))
+	for kind := range DefaultFileLayouts {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// ParseFileLayouts parses the layout templates given as kind=template, e.g. snippet=files/snippets/{{.SnippetType}}/{{.Priority}}_{{.Name}}.vcl
+func ParseFileLayouts(settings []string) (map[string]string, error) {
+	layouts := make(map[string]string, len(settings))
+	for _, s := range settings {
+		i := strings.Index(s, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid layout %q: must be kind=template", s)
+		}
+		layouts[s[:i]] = s[i+1:]
+	}
+	if err := ValidateFileLayouts(layouts); err != nil {
+		return nil, err
+	}
+	return layouts, nil
+}
+
+// ValidateFileLayouts returns an error if a kind is unknown or a template cannot be parsed
+func ValidateFileLayouts(layouts map[string]string) error {
+	for kind, layout := range layouts {
+		if _, ok := DefaultFileLayouts[kind]; !ok {
+			return fmt.Errorf("unknown layout kind %q: must be one of %s", kind, strings.Join(FileLayoutKinds(), ", "))
+		}
+		if _, err := parseFileLayout(kind, layout); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseFileLayout(kind, layout string) (*template.Template, error) {
+	t, err := template.New(kind).Option("missingkey=error").Parse(layout)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s layout: %w", kind, err)
+	}
+	return t, nil
+}
+
+// layoutPath renders the layout template of the kind, and returns the path of the file relative to FileDir
+func (c Config) layoutPath(kind string, vars FileVars) (string, error) {
+	layout, ok := c.FileLayouts[kind]
+	if !ok {
+		layout = DefaultFileLayouts[kind]
+	}
+	t, err := parseFileLayout(kind, layout)
+	if err != nil {
+		return "", err
+	}
+	var b bytes.Buffer
+	if err := t.Execute(&b, vars); err != nil {
+		return "", fmt.Errorf("invalid %s layout: %w", kind, err)
+	}
+
+	p := path.Clean(b.String())
+	if b.Len() == 0 || path.IsAbs(p) || p == "." || p == ".." || strings.HasPrefix(p, "../") {
+		return "", fmt.Errorf("%s layout %q renders %q, which is not a path in the module", kind, layout, b.String())
+	}
+	return p, nil
+}
+
+// snippetFileVars returns the variables of the snippet or dynamic snippet in the state
+func snippetFileVars(blockType string, snippet StateObject) FileVars {
+	name, _ := snippet.String("name")
+	snippetType, _ := snippet.String("type")
+	vars := FileVars{Type: blockType, SnippetType: snippetType, Name: normalize(name), Ext: "vcl"}
+	if v, err := snippet.Value("priority"); err == nil {
+		if priority, ok := v.(float64); ok {
+			vars.Priority = int(priority)
+		}
+	}
+	return vars
+}
@@ -0,0 +1,128 @@
+package terraformify
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+func TestLayoutPath(t *testing.T) {
+	c := Config{FileLayouts: map[string]string{
+		"snippet": "files/snippets/{{.SnippetType}}/{{.Priority}}_{{.Name}}.{{.Ext}}",
+		"vcl":     "files/../../{{.Name}}.{{.Ext}}",
+	}}
+	vars := FileVars{Type: "snippet", SnippetType: "recv", Priority: 110, Name: "headers", Ext: "vcl"}
+
+	p, err := c.layoutPath("snippet", vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "files/snippets/recv/110_headers.vcl"; p != want {
+		t.Errorf("got %s, want %s", p, want)
+	}
+	// The kinds not in the config use the default layouts
+	if p, err = c.layoutPath("content", FileVars{Name: "forbidden", Ext: "html"}); err != nil || p != "content/forbidden.html" {
+		t.Errorf("got %s, %v, want content/forbidden.html", p, err)
+	}
+	if _, err := c.layoutPath("vcl", vars); err == nil {
+		t.Error("a path out of the module is accepted")
+	}
+}
+
+func TestParseFileLayouts(t *testing.T) {
+	if _, err := ParseFileLayouts([]string{"snippet=files/{{.Name}}.vcl"}); err != nil {
+		t.Error(err)
+	}
+	for _, s := range []string{"files/{{.Name}}.vcl", "unknown=files/{{.Name}}", "vcl=files/{{.Name}.vcl"} {
+		if _, err := ParseFileLayouts([]string{s}); err == nil {
+			t.Errorf("%q is accepted", s)
+		}
+	}
+}
+
+func TestRewriteResourcesLayout(t *testing.T) {
+	config := Config{
+		ID: "6gjZ23Y0k6TApEs5PxzYuT",
+		FileLayouts: map[string]string{
+			"vcl":            "files/vcl/main/{{.Name}}.{{.Ext}}",
+			"snippet":        "files/snippets/{{.SnippetType}}/{{.Priority}}_{{.Name}}.{{.Ext}}",
+			"dynamicsnippet": "files/snippets/{{.SnippetType}}/{{.Priority}}_{{.Name}}.{{.Ext}}",
+		},
+	}
+	rawHCL, tfstate := loadTestFixture(t)
+	result, dir := rewriteTestResources(t, rawHCL, tfstate, config)
+
+	for _, p := range []string{
+		"files/snippets/recv/5_fastly_csi_init.vcl",
+		"files/snippets/recv/110_my_dynamic_snippet_one.vcl",
+		"logformat/weblogs.json",
+	} {
+		if !bytes.Contains(result, []byte(`file("${path.module}/`+p+`")`)) {
+			t.Errorf("%s is not referred to:\n%s", p, result)
+		}
+		if _, err := os.Stat(filepath.Join(dir, p)); err != nil {
+			t.Error(err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "vcl")); err == nil {
+		t.Error("the default vcl directory is created")
+	}
+}
+
+func TestRewriteResourcesLayoutCollision(t *testing.T) {
+	// The snippets and the dynamic snippets of the same type end up in one file
+	config := Config{
+		ID:        "6gjZ23Y0k6TApEs5PxzYuT",
+		Directory: t.TempDir(),
+		FileLayouts: map[string]string{
+			"snippet":        "files/snippets/{{.SnippetType}}.{{.Ext}}",
+			"dynamicsnippet": "files/snippets/{{.SnippetType}}.{{.Ext}}",
+		},
+	}
+	rawHCL, tfstate := loadTestFixture(t)
+	tfconf, err := LoadTFConf(rawHCL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serviceProp := NewVCLServiceResourceProp(config.ID, "service", 0)
+	if _, err := tfconf.RewriteResources(serviceProp, tfstate, config); err == nil {
+		t.Error("different contents are written to the same path")
+	}
+
+	// The same content can be written to the same path more than once
+	config.written = make(map[string]string)
+	vars := FileVars{Type: "snippet", SnippetType: "recv", Name: "headers", Ext: "vcl"}
+	for i := 0; i < 2; i++ {
+		if _, err := config.writeFile("snippet", vars, []byte("set req.http.X = \"1\";\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := config.writeFile("snippet", vars, []byte("set req.http.X = \"2\";\n")); err == nil {
+		t.Error("the file is overwritten with different content")
+	}
+}
+
+func TestFileReference(t *testing.T) {
+	src := `vcl {
+  content = file("${path.module}/files/vcl/main/main.vcl")
+}
+vcl {
+  content = file("./vcl/main.vcl")
+}
+`
+	f, diags := hclwrite.ParseConfig([]byte(src), "", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+	blocks := f.Body().Blocks()
+	// Both the references relative to the module and those of older configurations are resolved
+	for i, want := range []string{"files/vcl/main/main.vcl", "vcl/main.vcl"} {
+		if got := fileReference(blocks[i], "content"); got != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+	}
+}
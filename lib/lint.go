@@ -0,0 +1,446 @@
+package terraformify
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+const (
+	LintLevelOff     = "off"
+	LintLevelWarning = "warning"
+	LintLevelError   = "error"
+)
+
+// The log format Fastly sets when none is given
+const defaultLogFormat = `%h %l %u %t "%r" %>s %b`
+
+// LintRule is a check of the configuration for a common misconfiguration
+type LintRule struct {
+	ID          string
+	Description string
+	Level       string
+	check       func(l *linter) []LintFinding
+}
+
+// LintFinding is a problem found by a rule
+type LintFinding struct {
+	RuleID  string `json:"rule_id"`
+	Level   string `json:"level"`
+	Address string `json:"address"`
+	Message string `json:"message"`
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+}
+
+func (f LintFinding) String() string {
+	return fmt.Sprintf("%s:%d: %s: %s (%s)", f.File, f.Line, f.Level, f.Message, f.RuleID)
+}
+
+// LintConfig configures the rules.
+// Levels overrides the levels of the rules by ID, and a rule at LintLevelOff is disabled.
+// Production enables the rules that only apply to production services.
+type LintConfig struct {
+	Levels     map[string]string
+	Production bool
+}
+
+// LintRules are the available rules
+var LintRules = []LintRule{
+	{
+		ID:          "backend-without-healthcheck",
+		Description: "Backends should have a healthcheck so that unhealthy origins are not used",
+		Level:       LintLevelWarning,
+		check:       checkBackendWithoutHealthcheck,
+	},
+	{
+		ID:          "ssl-check-cert-disabled",
+		Description: "Backends should verify the certificates of the origins",
+		Level:       LintLevelError,
+		check:       checkSSLCheckCertDisabled,
+	},
+	{
+		ID:          "partial-shielding",
+		Description: "Shielding should be set on all backends or none of them",
+		Level:       LintLevelWarning,
+		check:       checkPartialShielding,
+	},
+	{
+		ID:          "logging-placeholder-format",
+		Description: "Logging endpoints should have a log format other than the default placeholder",
+		Level:       LintLevelWarning,
+		check:       checkLoggingPlaceholderFormat,
+	},
+	{
+		ID:          "unused-condition",
+		Description: "Conditions should be used by a block",
+		Level:       LintLevelWarning,
+		check:       checkUnusedCondition,
+	},
+	{
+		ID:          "request-setting-force-miss",
+		Description: "Request settings in production should not force cache misses",
+		Level:       LintLevelWarning,
+		check:       checkRequestSettingForceMiss,
+	},
+}
+
+// LintLevels returns the levels of the rules set in the config file.
+// YAML 1.1 reads an unquoted off as false, which is taken as off.
+func LintLevels(rules map[string]interface{}) (map[string]string, error) {
+	levels := make(map[string]string, len(rules))
+	for id, v := range rules {
+		switch v := v.(type) {
+		case string:
+			levels[id] = v
+		case bool:
+			if v {
+				return nil, fmt.Errorf("unknown level true for lint rule %q: must be one of off, warning, error", id)
+			}
+			levels[id] = LintLevelOff
+		default:
+			return nil, fmt.Errorf("unknown level %v for lint rule %q: must be one of off, warning, error", v, id)
+		}
+	}
+	return levels, nil
+}
+
+// ValidateLintConfig returns an error if the config refers to unknown rules or levels
+func ValidateLintConfig(cfg LintConfig) error {
+	known := make(map[string]bool, len(LintRules))
+	for _, r := range LintRules {
+		known[r.ID] = true
+	}
+	for id, level := range cfg.Levels {
+		if !known[id] {
+			return fmt.Errorf("unknown lint rule %q", id)
+		}
+		switch level {
+		case LintLevelOff, LintLevelWarning, LintLevelError:
+		default:
+			return fmt.Errorf("unknown level %q for lint rule %q: must be one of off, warning, error", level, id)
+		}
+	}
+	return nil
+}
+
+type linter struct {
+	tfconf     *TFConf
+	workingDir string
+	filename   string
+	lines      map[string]int
+	production bool
+}
+
+// Lint checks the configuration in the file of the working directory with the rules
+func Lint(workingDir, filename string, cfg LintConfig) ([]LintFinding, error) {
+	src, err := os.ReadFile(filepath.Join(workingDir, filename))
+	if err != nil {
+		return nil, err
+	}
+	tfconf, err := LoadTFConf(string(src))
+	if err != nil {
+		return nil, err
+	}
+	lines, err := blockLines(src, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &linter{
+		tfconf:     tfconf,
+		workingDir: workingDir,
+		filename:   filename,
+		lines:      lines,
+		production: cfg.Production,
+	}
+
+	findings := make([]LintFinding, 0)
+	for _, rule := range LintRules {
+		level := rule.Level
+		if v, ok := cfg.Levels[rule.ID]; ok {
+			level = v
+		}
+		if level == LintLevelOff {
+			continue
+		}
+		for _, f := range rule.check(l) {
+			f.RuleID = rule.ID
+			f.Level = level
+			f.File = filename
+			f.Line = l.lines[f.Address]
+			findings = append(findings, f)
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Line < findings[j].Line
+	})
+	return findings, nil
+}
+
+// blockLines maps the addresses of the resources and their nested blocks to the lines they start at
+func blockLines(src []byte, filename string) (map[string]int, error) {
+	f, diags := hclsyntax.ParseConfig(src, filename, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("errors: %s", diags)
+	}
+	body, ok := f.Body.(*hclsyntax.Body)
+	if !ok {
+		return nil, fmt.Errorf("%s: unexpected body type %T", filename, f.Body)
+	}
+
+	lines := make(map[string]int)
+	for _, block := range body.Blocks {
+		if block.Type != "resource" || len(block.Labels) != 2 {
+			continue
+		}
+		addr := block.Labels[0] + "." + block.Labels[1]
+		lines[addr] = block.TypeRange.Start.Line
+
+		// Key the nested blocks in the same way as nestedBlocks
+		counts := make(map[string]int)
+		for _, nested := range block.Body.Blocks {
+			var key string
+			if attr, ok := nested.Body.Attributes["name"]; ok {
+				v, diags := attr.Expr.Value(nil)
+				if !diags.HasErrors() && v.Type().FriendlyName() == "string" {
+					key = fmt.Sprintf("%s[%s]", nested.Type, strconv.Quote(v.AsString()))
+				}
+			}
+			if key == "" {
+				key = fmt.Sprintf("%s[%d]", nested.Type, counts[nested.Type])
+			}
+			counts[nested.Type]++
+			if _, ok := lines[addr+"."+key]; !ok {
+				lines[addr+"."+key] = nested.TypeRange.Start.Line
+			}
+		}
+	}
+	return lines, nil
+}
+
+// nestedBlocksOf calls fn with the address of each nested block of the type in the services
+func (l *linter) nestedBlocksOf(blockType string, fn func(addr string, block *hclwrite.Block)) {
+	resources := resourceBlocks(l.tfconf)
+	addrs := make([]string, 0, len(resources))
+	for addr := range resources {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+
+	for _, addr := range addrs {
+		if !isServiceType(resources[addr].Labels()[0]) {
+			continue
+		}
+		nested := nestedBlocks(resources[addr].Body())
+		keys := make([]string, 0, len(nested))
+		for key := range nested {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			block := nested[key]
+			if blockType == block.Type() || (strings.HasSuffix(blockType, "*") && strings.HasPrefix(block.Type(), strings.TrimSuffix(blockType, "*"))) {
+				fn(addr+"."+key, block)
+			}
+		}
+	}
+}
+
+func checkBackendWithoutHealthcheck(l *linter) []LintFinding {
+	findings := make([]LintFinding, 0)
+	l.nestedBlocksOf("backend", func(addr string, block *hclwrite.Block) {
+		if stringAttr(block, "healthcheck") == "" {
+			findings = append(findings, LintFinding{
+				Address: addr,
+				Message: fmt.Sprintf("backend %q has no healthcheck", stringAttr(block, "name")),
+			})
+		}
+	})
+	return findings
+}
+
+func checkSSLCheckCertDisabled(l *linter) []LintFinding {
+	findings := make([]LintFinding, 0)
+	l.nestedBlocksOf("backend", func(addr string, block *hclwrite.Block) {
+		if stringAttr(block, "use_ssl") == "true" && stringAttr(block, "ssl_check_cert") == "false" {
+			findings = append(findings, LintFinding{
+				Address: addr,
+				Message: fmt.Sprintf("backend %q does not verify the certificate of the origin (ssl_check_cert = false)", stringAttr(block, "name")),
+			})
+		}
+	})
+	return findings
+}
+
+func checkPartialShielding(l *linter) []LintFinding {
+	shielded := make(map[string][]string)
+	unshielded := make(map[string][]string)
+	services := make([]string, 0)
+	l.nestedBlocksOf("backend", func(addr string, block *hclwrite.Block) {
+		service := addr[:strings.Index(addr, ".backend[")]
+		if _, ok := shielded[service]; !ok {
+			if _, ok := unshielded[service]; !ok {
+				services = append(services, service)
+			}
+		}
+		if stringAttr(block, "shield") != "" {
+			shielded[service] = append(shielded[service], addr)
+		} else {
+			unshielded[service] = append(unshielded[service], addr)
+		}
+	})
+
+	findings := make([]LintFinding, 0)
+	for _, service := range services {
+		if len(shielded[service]) == 0 {
+			continue
+		}
+		for _, addr := range unshielded[service] {
+			findings = append(findings, LintFinding{
+				Address: addr,
+				Message: fmt.Sprintf("backend is not shielded while %d other backends are", len(shielded[service])),
+			})
+		}
+	}
+	return findings
+}
+
+func checkLoggingPlaceholderFormat(l *linter) []LintFinding {
+	findings := make([]LintFinding, 0)
+	l.nestedBlocksOf("logging_*", func(addr string, block *hclwrite.Block) {
+		format := stringAttr(block, "format")
+		if path := fileReference(block, "format"); path != "" {
+			b, err := os.ReadFile(filepath.Join(l.workingDir, path))
+			if err != nil {
+				// The format cannot be checked without the file
+				return
+			}
+			format = string(b)
+		}
+		format = strings.TrimSpace(format)
+		if format == "" || format == defaultLogFormat {
+			findings = append(findings, LintFinding{
+				Address: addr,
+				Message: fmt.Sprintf("logging endpoint %q uses the placeholder log format", stringAttr(block, "name")),
+			})
+		}
+	})
+	return findings
+}
+
+func checkUnusedCondition(l *linter) []LintFinding {
+	findings := make([]LintFinding, 0)
+	for _, n := range l.tfconf.BuildGraph().Nodes {
+		if n.Unused && strings.Contains(n.Address, ".condition[") {
+			findings = append(findings, LintFinding{
+				Address: n.Address,
+				Message: "condition is not used by any block",
+			})
+		}
+	}
+	return findings
+}
+
+func checkRequestSettingForceMiss(l *linter) []LintFinding {
+	findings := make([]LintFinding, 0)
+	if !l.production {
+		return findings
+	}
+	l.nestedBlocksOf("request_setting", func(addr string, block *hclwrite.Block) {
+		if stringAttr(block, "force_miss") == "true" {
+			findings = append(findings, LintFinding{
+				Address: addr,
+				Message: fmt.Sprintf("request setting %q forces cache misses in production", stringAttr(block, "name")),
+			})
+		}
+	})
+	return findings
+}
+
+// LintSARIF renders the findings as a SARIF 2.1.0 log
+func LintSARIF(findings []LintFinding, toolVersion string) ([]byte, error) {
+	type message struct {
+		Text string `json:"text"`
+	}
+	type rule struct {
+		ID                   string  `json:"id"`
+		ShortDescription     message `json:"shortDescription"`
+		DefaultConfiguration struct {
+			Level string `json:"level"`
+		} `json:"defaultConfiguration"`
+	}
+	type location struct {
+		PhysicalLocation struct {
+			ArtifactLocation struct {
+				URI string `json:"uri"`
+			} `json:"artifactLocation"`
+			Region struct {
+				StartLine int `json:"startLine"`
+			} `json:"region"`
+		} `json:"physicalLocation"`
+		LogicalLocations []struct {
+			FullyQualifiedName string `json:"fullyQualifiedName"`
+		} `json:"logicalLocations"`
+	}
+	type result struct {
+		RuleID    string     `json:"ruleId"`
+		Level     string     `json:"level"`
+		Message   message    `json:"message"`
+		Locations []location `json:"locations"`
+	}
+
+	rules := make([]rule, 0, len(LintRules))
+	for _, r := range LintRules {
+		sr := rule{ID: r.ID, ShortDescription: message{Text: r.Description}}
+		sr.DefaultConfiguration.Level = r.Level
+		rules = append(rules, sr)
+	}
+
+	results := make([]result, 0, len(findings))
+	for _, f := range findings {
+		var loc location
+		loc.PhysicalLocation.ArtifactLocation.URI = f.File
+		loc.PhysicalLocation.Region.StartLine = f.Line
+		loc.LogicalLocations = append(loc.LogicalLocations, struct {
+			FullyQualifiedName string `json:"fullyQualifiedName"`
+		}{FullyQualifiedName: f.Address})
+		results = append(results, result{
+			RuleID:    f.RuleID,
+			Level:     f.Level,
+			Message:   message{Text: f.Message},
+			Locations: []location{loc},
+		})
+	}
+
+	log := map[string]interface{}{
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"version": "2.1.0",
+		"runs": []interface{}{
+			map[string]interface{}{
+				"tool": map[string]interface{}{
+					"driver": map[string]interface{}{
+						"name":           "terraformify",
+						"version":        toolVersion,
+						"informationUri": "https://github.com/hrmsk66/terraformify",
+						"rules":          rules,
+					},
+				},
+				"results": results,
+			},
+		},
+	}
+	b, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
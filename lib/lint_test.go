@@ -0,0 +1,181 @@
+package terraformify
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+const lintHCL = `resource "fastly_service_vcl" "service" {
+  name = "example"
+
+  backend {
+    address        = "origin.example.com"
+    healthcheck    = "check"
+    name           = "origin"
+    shield         = "tokyo-jp"
+    ssl_check_cert = false
+    use_ssl        = true
+  }
+  backend {
+    address = "static.example.com"
+    name    = "static"
+  }
+  condition {
+    name      = "unused"
+    statement = "true"
+    type      = "REQUEST"
+  }
+  healthcheck {
+    host = "origin.example.com"
+    name = "check"
+    path = "/"
+  }
+  logging_s3 {
+    bucket_name = "logs"
+    format      = file("./logformat/s3.txt")
+    name        = "s3"
+  }
+  request_setting {
+    force_miss = true
+    name       = "debug"
+  }
+}
+`
+
+func TestLint(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.tf"), []byte(lintHCL), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "logformat"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "logformat", "s3.txt"), []byte(defaultLogFormat+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	summarize := func(findings []LintFinding) []string {
+		got := make([]string, 0, len(findings))
+		for _, f := range findings {
+			got = append(got, f.String())
+		}
+		return got
+	}
+
+	findings, err := Lint(dir, "main.tf", LintConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`main.tf:4: error: backend "origin" does not verify the certificate of the origin (ssl_check_cert = false) (ssl-check-cert-disabled)`,
+		`main.tf:12: warning: backend "static" has no healthcheck (backend-without-healthcheck)`,
+		`main.tf:12: warning: backend is not shielded while 1 other backends are (partial-shielding)`,
+		`main.tf:16: warning: condition is not used by any block (unused-condition)`,
+		`main.tf:26: warning: logging endpoint "s3" uses the placeholder log format (logging-placeholder-format)`,
+	}
+	if got := summarize(findings); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	findings, err = Lint(dir, "main.tf", LintConfig{
+		Levels: map[string]string{
+			"ssl-check-cert-disabled":     LintLevelOff,
+			"backend-without-healthcheck": LintLevelOff,
+			"partial-shielding":           LintLevelOff,
+			"unused-condition":            LintLevelOff,
+			"logging-placeholder-format":  LintLevelOff,
+			"request-setting-force-miss":  LintLevelError,
+		},
+		Production: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{
+		`main.tf:31: error: request setting "debug" forces cache misses in production (request-setting-force-miss)`,
+	}
+	if got := summarize(findings); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	b, err := LintSARIF(findings, "dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sarif struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				Locations []struct {
+					PhysicalLocation struct {
+						Region struct {
+							StartLine int `json:"startLine"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(b, &sarif); err != nil {
+		t.Fatal(err)
+	}
+	if sarif.Version != "2.1.0" || len(sarif.Runs) != 1 || len(sarif.Runs[0].Results) != 1 {
+		t.Fatalf("unexpected SARIF log: %s", b)
+	}
+	if r := sarif.Runs[0].Results[0]; r.RuleID != "request-setting-force-miss" || r.Locations[0].PhysicalLocation.Region.StartLine != 31 {
+		t.Errorf("unexpected SARIF result: %s", b)
+	}
+}
+
+func TestValidateLintConfig(t *testing.T) {
+	if err := ValidateLintConfig(LintConfig{Levels: map[string]string{"no-such-rule": LintLevelOff}}); err == nil {
+		t.Error("expected an error for an unknown rule")
+	}
+	if err := ValidateLintConfig(LintConfig{Levels: map[string]string{"partial-shielding": "fatal"}}); err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+}
+
+func TestLintLevelsFromConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".terraformify.yaml")
+	config := `lint:
+  rules:
+    backend-without-healthcheck: off
+    partial-shielding: error
+    unused-condition: "off"
+`
+	if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	levels, err := LintLevels(v.GetStringMap("lint.rules"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"backend-without-healthcheck": LintLevelOff,
+		"partial-shielding":           LintLevelError,
+		"unused-condition":            LintLevelOff,
+	}
+	if !reflect.DeepEqual(levels, expected) {
+		t.Errorf("got %v, want %v", levels, expected)
+	}
+	if err := ValidateLintConfig(LintConfig{Levels: levels}); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := LintLevels(map[string]interface{}{"partial-shielding": true}); err == nil {
+		t.Error("expected an error for true")
+	}
+}
@@ -0,0 +1,87 @@
+package terraformify
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Values shorter than this are not replaced with template variables, as they may appear in the format by chance
+const minLogFormatVarLength = 4
+
+// logFormatVars returns the environment-specific values of the service and the logging endpoint keyed by the names of the template variables
+func logFormatVars(service, logging StateObject) map[string]string {
+	vars := make(map[string]string)
+	add := func(name string, o StateObject, key string) {
+		if v, err := o.String(key); err == nil && len(v) >= minLogFormatVarLength {
+			vars[name] = v
+		}
+	}
+	add("service_name", service, "name")
+	add("service_id", service, "id")
+	add("region", logging, "region")
+	return vars
+}
+
+// escapeTemplate escapes the template sequences so that templatefile renders the string as is
+func escapeTemplate(s string) string {
+	s = strings.ReplaceAll(s, "${", "$${")
+	return strings.ReplaceAll(s, "%{", "%%{")
+}
+
+// buildLogFormatTemplate returns the log format as a template, with the values of vars replaced with the variables.
+// The names of the variables in the template are returned as well.
+func buildLogFormatTemplate(format string, vars map[string]string) (string, []string) {
+	tmpl := escapeTemplate(format)
+
+	// Replace the longer values first, as they may contain the shorter ones
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if len(vars[names[i]]) != len(vars[names[j]]) {
+			return len(vars[names[i]]) > len(vars[names[j]])
+		}
+		return names[i] < names[j]
+	})
+
+	used := make([]string, 0, len(names))
+	for _, name := range names {
+		v := escapeTemplate(vars[name])
+		if !strings.Contains(tmpl, v) {
+			continue
+		}
+		tmpl = strings.ReplaceAll(tmpl, v, "${"+name+"}")
+		used = append(used, name)
+	}
+	sort.Strings(used)
+	return tmpl, used
+}
+
+// buildTemplateFileFunction returns the templatefile function expression that renders the template with the values of the variables
+func buildTemplateFileFunction(path string, vars map[string]string, names []string) hclwrite.Tokens {
+	tokens := hclwrite.Tokens{
+		{Type: hclsyntax.TokenIdent, Bytes: []byte("templatefile")},
+		{Type: hclsyntax.TokenOParen, Bytes: []byte{'('}},
+	}
+	tokens = append(tokens, buildModulePath(path)...)
+	tokens = append(tokens,
+		&hclwrite.Token{Type: hclsyntax.TokenComma, Bytes: []byte{','}},
+		&hclwrite.Token{Type: hclsyntax.TokenOBrace, Bytes: []byte{'{'}, SpacesBefore: 1},
+		&hclwrite.Token{Type: hclsyntax.TokenNewline, Bytes: []byte("\n")},
+	)
+	for _, name := range names {
+		tokens = append(tokens, &hclwrite.Token{Type: hclsyntax.TokenIdent, Bytes: []byte(name)})
+		tokens = append(tokens, &hclwrite.Token{Type: hclsyntax.TokenEqual, Bytes: []byte{'='}})
+		tokens = append(tokens, hclwrite.TokensForValue(cty.StringVal(vars[name]))...)
+		tokens = append(tokens, &hclwrite.Token{Type: hclsyntax.TokenNewline, Bytes: []byte("\n")})
+	}
+	return append(tokens,
+		&hclwrite.Token{Type: hclsyntax.TokenCBrace, Bytes: []byte{'}'}},
+		&hclwrite.Token{Type: hclsyntax.TokenCParen, Bytes: []byte{')'}},
+	)
+}
@@ -0,0 +1,79 @@
+package terraformify
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestBuildLogFormatTemplate(t *testing.T) {
+	vars := map[string]string{
+		"service_name": "www.example.com",
+		"service_id":   "6gjZ23Y0k6TApEs5PxzYuT",
+		"region":       "us-east-1",
+	}
+	testCases := []struct {
+		format   string
+		expected string
+		names    []string
+	}{
+		{
+			format:   `{"service": "www.example.com", "region": "us-east-1", "url": "%{json.escape(req.url)}V"}`,
+			expected: `{"service": "${service_name}", "region": "${region}", "url": "%%{json.escape(req.url)}V"}`,
+			names:    []string{"region", "service_name"},
+		},
+		{
+			// The service name has the region in it
+			format:   `%h www.example.com.us-east-1 ${literal}`,
+			expected: `%h ${service_name}.${region} $${literal}`,
+			names:    []string{"region", "service_name"},
+		},
+		{
+			format:   `%h %l %u %t "%r" %>s %b`,
+			expected: `%h %l %u %t "%r" %>s %b`,
+			names:    []string{},
+		},
+	}
+
+	for _, tt := range testCases {
+		tmpl, names := buildLogFormatTemplate(tt.format, vars)
+		if tmpl != tt.expected {
+			t.Errorf("got %s, want %s", tmpl, tt.expected)
+		}
+		if !reflect.DeepEqual(names, tt.names) {
+			t.Errorf("got %v, want %v", names, tt.names)
+		}
+
+		// templatefile renders the template back to the format
+		expr, diags := hclsyntax.ParseTemplate([]byte(tmpl), "", hcl.Pos{Line: 1, Column: 1})
+		if diags.HasErrors() {
+			t.Fatal(diags)
+		}
+		values := make(map[string]cty.Value)
+		for name, v := range vars {
+			values[name] = cty.StringVal(v)
+		}
+		v, diags := expr.Value(&hcl.EvalContext{Variables: values})
+		if diags.HasErrors() {
+			t.Fatal(diags)
+		}
+		if v.AsString() != tt.format {
+			t.Errorf("rendered to %s, want %s", v.AsString(), tt.format)
+		}
+	}
+}
+
+func TestBuildTemplateFileFunction(t *testing.T) {
+	vars := map[string]string{"service_name": `a "quoted" ${name}`}
+	tokens := buildTemplateFileFunction("logformat/weblogs.json.tftpl", vars, []string{"service_name"})
+	expected := `templatefile("${path.module}/logformat/weblogs.json.tftpl", {
+  service_name = "a \"quoted\" $${name}"
+})`
+	if got := string(hclwrite.Format(tokens.Bytes())); got != expected {
+		t.Errorf("got %s, want %s", got, expected)
+	}
+}
@@ -0,0 +1,397 @@
+package terraformify
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// mergeableResource describes the associated resources that are merged into one for_each resource per service
+type mergeableResource struct {
+	// Type of the nested block of the service, and its ID attribute
+	attrType string
+	idName   string
+	// Attribute, or dynamic block, that takes the value of the local map for each key
+	valueName string
+}
+
+var mergeableResources = map[string]mergeableResource{
+	"fastly_service_acl_entries":             {attrType: "acl", idName: "acl_id", valueName: "entry"},
+	"fastly_service_dictionary_items":        {attrType: "dictionary", idName: "dictionary_id", valueName: "items"},
+	"fastly_service_dynamic_snippet_content": {attrType: "dynamicsnippet", idName: "snippet_id", valueName: "content"},
+}
+
+// mergedLocalName returns the name of the local map that has the values of the merged resource, e.g. service_dictionary_items
+func mergedLocalName(serviceProp *VCLServiceResourceProp, resourceType string) string {
+	return serviceProp.GetNormalizedName() + "_" + strings.TrimPrefix(resourceType, "fastly_service_")
+}
+
+// mergeForEachResources replaces the ACL, dictionary and dynamic snippet resources, which have a single-element for_each each,
+// with one resource per type that has for_each over all of them. The values of the resources are moved to a local map keyed by name.
+func (tfconf *TFConf) mergeForEachResources(serviceProp *VCLServiceResourceProp, c Config) error {
+	blocks := tfconf.Body().Blocks()
+	grouped := make(map[string][]*hclwrite.Block)
+	for _, block := range blocks {
+		labels := block.Labels()
+		if block.Type() != "resource" || len(labels) != 2 {
+			continue
+		}
+		if _, ok := mergeableResources[labels[0]]; ok {
+			grouped[labels[0]] = append(grouped[labels[0]], block)
+		}
+	}
+	if len(grouped) == 0 {
+		return nil
+	}
+
+	// Rebuild the file, putting the merged resources where the first resource of the type was
+	f := hclwrite.NewEmptyFile()
+	for _, block := range blocks {
+		resourceType := ""
+		if labels := block.Labels(); block.Type() == "resource" && len(labels) == 2 {
+			resourceType = labels[0]
+		}
+		group, ok := grouped[resourceType]
+		if ok && group == nil {
+			// Already merged
+			continue
+		}
+		if len(f.Body().Blocks()) > 0 {
+			f.Body().AppendNewline()
+		}
+		if !ok {
+			f.Body().AppendBlock(block)
+			continue
+		}
+		locals, merged, err := buildMergedResource(serviceProp, resourceType, group, c)
+		if err != nil {
+			return err
+		}
+		f.Body().AppendBlock(locals)
+		f.Body().AppendNewline()
+		f.Body().AppendBlock(merged)
+		grouped[resourceType] = nil
+	}
+	tfconf.File = f
+	return nil
+}
+
+func buildMergedResource(serviceProp *VCLServiceResourceProp, resourceType string, blocks []*hclwrite.Block, c Config) (*hclwrite.Block, *hclwrite.Block, error) {
+	r := mergeableResources[resourceType]
+	localName := mergedLocalName(serviceProp, resourceType)
+
+	// The values of the resources keyed by the names of the nested blocks in the service
+	values := make(map[string]hclwrite.Tokens, len(blocks))
+	for _, block := range blocks {
+		attr := block.Body().GetAttribute("for_each")
+		if attr == nil {
+			return nil, nil, fmt.Errorf("tfconf: %s.%s has no for_each", resourceType, block.Labels()[1])
+		}
+		m := forEachNamePattern.FindSubmatch(attr.Expr().BuildTokens(nil).Bytes())
+		if m == nil {
+			return nil, nil, fmt.Errorf("tfconf: unexpected for_each in %s.%s", resourceType, block.Labels()[1])
+		}
+		tokens, err := mergedValue(block, r.valueName)
+		if err != nil {
+			return nil, nil, err
+		}
+		values[string(m[1])] = tokens
+	}
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	localMap := hclwrite.Tokens{
+		{Type: hclsyntax.TokenOBrace, Bytes: []byte{'{'}},
+		{Type: hclsyntax.TokenNewline, Bytes: []byte("\n")},
+	}
+	for _, k := range keys {
+		localMap = append(localMap, hclwrite.TokensForValue(cty.StringVal(k))...)
+		localMap = append(localMap, &hclwrite.Token{Type: hclsyntax.TokenEqual, Bytes: []byte{'='}})
+		localMap = append(localMap, values[k]...)
+		localMap = append(localMap, &hclwrite.Token{Type: hclsyntax.TokenNewline, Bytes: []byte("\n")})
+	}
+	localMap = append(localMap, &hclwrite.Token{Type: hclsyntax.TokenCBrace, Bytes: []byte{'}'}})
+
+	locals := hclwrite.NewBlock("locals", nil)
+	locals.Body().SetAttributeRaw(localName, localMap)
+
+	merged := hclwrite.NewBlock("resource", []string{resourceType, serviceProp.GetNormalizedName()})
+	body := merged.Body()
+	body.SetAttributeTraversal(r.idName, buildForEachIDRef(r.idName))
+	valueTokens, err := buildExpression(fmt.Sprintf("local.%s[each.key]", localName))
+	if err != nil {
+		return nil, nil, err
+	}
+	if r.valueName != "entry" {
+		body.SetAttributeRaw(r.valueName, valueTokens)
+	}
+	body.SetAttributeTraversal("service_id", buildServiceIDRef(serviceProp))
+	if c.ManageAll {
+		body.SetAttributeValue(manageAttrs[resourceType], cty.BoolVal(true))
+	}
+	if r.valueName == "entry" {
+		body.AppendNewline()
+		dynamic := body.AppendNewBlock("dynamic", []string{"entry"})
+		dynamic.Body().SetAttributeRaw("for_each", valueTokens)
+		content := dynamic.Body().AppendNewBlock("content", nil).Body()
+		for _, key := range aclEntryKeys {
+			content.SetAttributeTraversal(key, hcl.Traversal{
+				hcl.TraverseRoot{Name: "entry"},
+				hcl.TraverseAttr{Name: "value"},
+				hcl.TraverseAttr{Name: key},
+			})
+		}
+	}
+
+	forEach, err := buildExpression(fmt.Sprintf("{\n  for d in %s.%s : d.name => d if contains(keys(local.%s), d.name)\n}",
+		serviceProp.GetRef(), r.attrType, localName))
+	if err != nil {
+		return nil, nil, err
+	}
+	body.SetAttributeRaw("for_each", forEach)
+
+	return locals, merged, nil
+}
+
+// mergedValue returns the expression of the value of the resource to be put in the local map
+func mergedValue(block *hclwrite.Block, valueName string) (hclwrite.Tokens, error) {
+	body := block.Body()
+	if valueName != "entry" {
+		attr := body.GetAttribute(valueName)
+		if attr == nil {
+			return nil, fmt.Errorf("tfconf: %s.%s has no %s", block.Labels()[0], block.Labels()[1], valueName)
+		}
+		return attr.Expr().BuildTokens(nil), nil
+	}
+
+	// ACL entries extracted to a file are already in a dynamic block
+	for _, nested := range body.Blocks() {
+		if nested.Type() == "dynamic" {
+			return nested.Body().GetAttribute("for_each").Expr().BuildTokens(nil), nil
+		}
+	}
+
+	// Otherwise the entry blocks become a list of objects
+	tokens := hclwrite.Tokens{
+		{Type: hclsyntax.TokenOBrack, Bytes: []byte{'['}},
+		{Type: hclsyntax.TokenNewline, Bytes: []byte("\n")},
+	}
+	for _, entry := range body.Blocks() {
+		if entry.Type() != "entry" {
+			continue
+		}
+		tokens = append(tokens,
+			&hclwrite.Token{Type: hclsyntax.TokenOBrace, Bytes: []byte{'{'}},
+			&hclwrite.Token{Type: hclsyntax.TokenNewline, Bytes: []byte("\n")},
+		)
+		for _, key := range aclEntryKeys {
+			tokens = append(tokens,
+				&hclwrite.Token{Type: hclsyntax.TokenIdent, Bytes: []byte(key)},
+				&hclwrite.Token{Type: hclsyntax.TokenEqual, Bytes: []byte{'='}},
+			)
+			if attr := entry.Body().GetAttribute(key); attr != nil {
+				tokens = append(tokens, attr.Expr().BuildTokens(nil)...)
+			} else {
+				tokens = append(tokens, &hclwrite.Token{Type: hclsyntax.TokenIdent, Bytes: []byte("null")})
+			}
+			tokens = append(tokens, &hclwrite.Token{Type: hclsyntax.TokenNewline, Bytes: []byte("\n")})
+		}
+		tokens = append(tokens,
+			&hclwrite.Token{Type: hclsyntax.TokenCBrace, Bytes: []byte{'}'}},
+			&hclwrite.Token{Type: hclsyntax.TokenComma, Bytes: []byte{','}},
+			&hclwrite.Token{Type: hclsyntax.TokenNewline, Bytes: []byte("\n")},
+		)
+	}
+	return append(tokens, &hclwrite.Token{Type: hclsyntax.TokenCBrack, Bytes: []byte{']'}}), nil
+}
+
+// MergeForEachInstances moves the instances of the ACL, dictionary and dynamic snippet resources of the props
+// into one resource per type named after the service, as mergeForEachResources does in the configuration.
+// The index keys of the instances are set to the names of the props. The props of the other types are returned.
+func (s *TFState) MergeForEachInstances(serviceProp *VCLServiceResourceProp, props ...TFBlockProp) (*TFState, []TFBlockProp, error) {
+	keys := make(map[string]map[string]string)
+	rest := make([]TFBlockProp, 0, len(props))
+	for _, prop := range props {
+		t := prop.GetType()
+		if _, ok := mergeableResources[t]; !ok {
+			rest = append(rest, prop)
+			continue
+		}
+		if keys[t] == nil {
+			keys[t] = make(map[string]string)
+		}
+		keys[t][prop.GetNormalizedName()] = prop.GetName()
+	}
+
+	newState := s
+	types := make([]string, 0, len(keys))
+	for t := range keys {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	for _, t := range types {
+		var err error
+		newState, err = newState.MergeInstances(t, serviceProp.GetNormalizedName(), keys[t])
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return newState, rest, nil
+}
+
+var mergedForEachPattern = regexp.MustCompile(`contains\(\s*keys\(\s*local\.([A-Za-z0-9_-]+)\s*\)\s*,\s*d\.name\s*\)`)
+
+// forEachSelection is the nested blocks of a service that the for_each of an associated resource picks by name
+type forEachSelection struct {
+	// Address of the service and the type of the nested blocks, e.g. fastly_service_vcl.service and dictionary
+	service   string
+	blockType string
+	// Blocks in the form of the resources that have a single-element for_each, keyed by the names of the nested blocks.
+	// Those of a merged resource are built from the values in the local map.
+	blocks map[string]*hclwrite.Block
+}
+
+// target returns the address of the nested block of the service, e.g. fastly_service_vcl.service.dictionary["name"]
+func (s forEachSelection) target(name string) string {
+	return nestedAddress(s.service, s.blockType, name)
+}
+
+// names returns the names of the nested blocks in order
+func (s forEachSelection) names() []string {
+	names := make([]string, 0, len(s.blocks))
+	for name := range s.blocks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseForEach returns the nested blocks that the for_each of the resource picks, either one by name as buildForEach does,
+// or all the keys of a local map as mergeForEachResources does. locals is the result of localMaps.
+func parseForEach(block *hclwrite.Block, locals map[string]map[string]hclwrite.Tokens) (forEachSelection, bool) {
+	attr := block.Body().GetAttribute("for_each")
+	if attr == nil {
+		return forEachSelection{}, false
+	}
+	expr := string(attr.Expr().BuildTokens(nil).Bytes())
+
+	// e.g. for d in fastly_service_vcl.service.dictionary : ...
+	var parts []string
+	fields := strings.Fields(expr)
+	for i, f := range fields {
+		if f == "in" && i+1 < len(fields) {
+			parts = strings.Split(fields[i+1], ".")
+			break
+		}
+	}
+	if len(parts) != 3 {
+		return forEachSelection{}, false
+	}
+	s := forEachSelection{
+		service:   parts[0] + "." + parts[1],
+		blockType: parts[2],
+		blocks:    make(map[string]*hclwrite.Block),
+	}
+
+	if m := forEachNamePattern.FindStringSubmatch(expr); m != nil {
+		s.blocks[m[1]] = block
+		return s, true
+	}
+	m := mergedForEachPattern.FindStringSubmatch(expr)
+	if m == nil {
+		return forEachSelection{}, false
+	}
+	values, ok := locals[m[1]]
+	if !ok {
+		log.Printf("[WARN] local.%s that for_each of %s.%s refers to is not found", m[1], block.Labels()[0], block.Labels()[1])
+		return forEachSelection{}, false
+	}
+	for name, value := range values {
+		s.blocks[name] = unmergedBlock(block.Labels()[0], name, value)
+	}
+	return s, true
+}
+
+// unmergedBlock builds the resource block that has the value of the local map, as it was before mergeForEachResources
+func unmergedBlock(resourceType, name string, value hclwrite.Tokens) *hclwrite.Block {
+	block := hclwrite.NewBlock("resource", []string{resourceType, name})
+	valueName := mergeableResources[resourceType].valueName
+	if valueName == "entry" {
+		// Either the entries read from a file or a list of them
+		block.Body().AppendNewBlock("dynamic", []string{"entry"}).Body().SetAttributeRaw("for_each", value)
+		return block
+	}
+	block.Body().SetAttributeRaw(valueName, value)
+	return block
+}
+
+// localMaps returns the maps with string keys defined in the locals blocks, keyed by the names of the locals.
+// The values are the expressions of the elements of the maps.
+func localMaps(bodies ...*hclwrite.Body) map[string]map[string]hclwrite.Tokens {
+	locals := make(map[string]map[string]hclwrite.Tokens)
+	for _, body := range bodies {
+		for _, block := range body.Blocks() {
+			if block.Type() != "locals" {
+				continue
+			}
+			for name, attr := range block.Body().Attributes() {
+				// A trailing newline is required to close heredocs
+				src := append(attr.Expr().BuildTokens(nil).Bytes(), '\n')
+				expr, diags := hclsyntax.ParseExpression(src, "", hcl.Pos{Line: 1, Column: 1})
+				if diags.HasErrors() {
+					continue
+				}
+				obj, ok := expr.(*hclsyntax.ObjectConsExpr)
+				if !ok {
+					continue
+				}
+				values := make(map[string]hclwrite.Tokens, len(obj.Items))
+				for _, item := range obj.Items {
+					key, diags := item.KeyExpr.Value(nil)
+					if diags.HasErrors() || key.Type() != cty.String || key.IsNull() {
+						continue
+					}
+					r := item.ValueExpr.Range()
+					tokens, err := buildExpression(string(src[r.Start.Byte:r.End.Byte]))
+					if err != nil {
+						continue
+					}
+					values[key.AsString()] = tokens
+				}
+				locals[name] = values
+			}
+		}
+	}
+	return locals
+}
+
+// ReserveMergedNames checks that the names of the resources merged by MergeForEach, which are named after the service,
+// are not in use, e.g. by the resources in the root module, and reserves them so that no associated resource is assigned them.
+func (n *Namer) ReserveMergedNames(serviceProp *VCLServiceResourceProp) error {
+	types := make([]string, 0, len(mergeableResources))
+	for t := range mergeableResources {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+
+	name := serviceProp.GetNormalizedName()
+	for _, t := range types {
+		if n.Reserved(t, name) {
+			return fmt.Errorf("%s.%s already exists", t, name)
+		}
+	}
+	for _, t := range types {
+		n.Reserve(t, name)
+	}
+	return nil
+}
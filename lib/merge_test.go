@@ -0,0 +1,130 @@
+package terraformify
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRewriteResourcesMergeForEach(t *testing.T) {
+	config := Config{
+		ID:               "6gjZ23Y0k6TApEs5PxzYuT",
+		ExtractThreshold: 2,
+		MergeForEach:     true,
+	}
+	rawHCL, tfstate := loadTestFixture(t)
+	result, _ := rewriteTestResources(t, rawHCL, tfstate, config)
+
+	// Extracted dictionaries are read from the files in the local map
+	expected := []string{`locals {
+  service_dictionary_items = {
+    "config_table" = {
+      "maintenance" = "true"
+      "otherconfig" = "false"
+    }
+    "redirect_table" = jsondecode(file("${path.module}/dictionaries/redirect_table.json"))
+  }
+}
+
+resource "fastly_service_dictionary_items" "service" {
+  dictionary_id = each.value.dictionary_id
+  items         = local.service_dictionary_items[each.key]
+  service_id    = fastly_service_vcl.service.id
+  for_each = {
+    for d in fastly_service_vcl.service.dictionary : d.name => d if contains(keys(local.service_dictionary_items), d.name)
+  }
+}
+`, `
+  dynamic "entry" {
+    for_each = local.service_acl_entries[each.key]
+    content {
+      ip      = entry.value.ip
+      subnet  = entry.value.subnet
+      negated = entry.value.negated
+      comment = entry.value.comment
+    }
+  }
+`, `    "My Dynamic Snippet One" = file("${path.module}/vcl/dsnippet_my_dynamic_snippet_one.vcl")
+`}
+	for _, e := range expected {
+		if !bytes.Contains(result, []byte(e)) {
+			t.Errorf("expected:\n%s\nresult:\n%s", e, result)
+		}
+	}
+	for _, name := range []string{"allow_list", "config_table", "my_dynamic_snippet_one"} {
+		if bytes.Contains(result, []byte(`" "`+name+`" {`)) {
+			t.Errorf("%s is not merged:\n%s", name, result)
+		}
+	}
+}
+
+func TestMergeForEachInstances(t *testing.T) {
+	_, tfstate := loadTestFixture(t)
+	serviceProp := NewVCLServiceResourceProp("6gjZ23Y0k6TApEs5PxzYuT", "service", 0)
+	props := []TFBlockProp{
+		NewDictionaryResourceProp("dict1", "config_table", serviceProp),
+		NewDictionaryResourceProp("dict2", "redirect_table", serviceProp),
+		NewWAFResourceProp("waf1", serviceProp),
+	}
+	props[0].SetNormalizedName("config_table")
+	props[1].SetNormalizedName("redirect_table")
+	props[2].SetNormalizedName("waf")
+
+	newState, rest, err := tfstate.MergeForEachInstances(serviceProp, props...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rest) != 1 || rest[0] != props[2] {
+		t.Errorf("unexpected props left: %v", rest)
+	}
+
+	r, err := newState.Resource("fastly_service_dictionary_items", "service")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var addrs []string
+	for _, i := range r.Instances() {
+		addrs = append(addrs, i.Address())
+	}
+	expected := []string{
+		`fastly_service_dictionary_items.service["config_table"]`,
+		`fastly_service_dictionary_items.service["redirect_table"]`,
+	}
+	if len(addrs) != len(expected) || addrs[0] != expected[0] || addrs[1] != expected[1] {
+		t.Errorf("got %v, want %v", addrs, expected)
+	}
+	if _, err := newState.Resource("fastly_service_dictionary_items", "config_table"); err == nil {
+		t.Error("config_table is left in the state")
+	}
+	if _, err := newState.Resource("fastly_service_acl_entries", "allow_list"); err != nil {
+		t.Errorf("resources of the other types are changed: %v", err)
+	}
+}
+
+func TestReserveMergedNames(t *testing.T) {
+	serviceProp := NewVCLServiceResourceProp("6gjZ23Y0k6TApEs5PxzYuT", "service", 0)
+
+	namer, err := NewNamer("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// e.g. a resource of another service in the root module
+	namer.Reserve("fastly_service_dictionary_items", "service")
+	if err := namer.ReserveMergedNames(serviceProp); err == nil {
+		t.Error("the name in use is reserved")
+	}
+
+	namer, err = NewNamer("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := namer.ReserveMergedNames(serviceProp); err != nil {
+		t.Fatal(err)
+	}
+	dict := NewDictionaryResourceProp("dict1", "service", serviceProp)
+	if err := namer.Assign(dict); err != nil {
+		t.Fatal(err)
+	}
+	if got := dict.GetNormalizedName(); got != "service_2" {
+		t.Errorf("got %s, want service_2", got)
+	}
+}
@@ -0,0 +1,113 @@
+package terraformify
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// instanceAddress is the address of a resource instance in the state.
+type instanceAddress struct {
+	Type     string
+	Name     string
+	IndexKey interface{}
+}
+
+func (a instanceAddress) String() string {
+	switch k := a.IndexKey.(type) {
+	case string:
+		return fmt.Sprintf("%s.%s[%q]", a.Type, a.Name, k)
+	case float64:
+		return fmt.Sprintf("%s.%s[%d]", a.Type, a.Name, int(k))
+	default:
+		return fmt.Sprintf("%s.%s", a.Type, a.Name)
+	}
+}
+
+func (a instanceAddress) Traversal() hcl.Traversal {
+	t := hcl.Traversal{
+		hcl.TraverseRoot{Name: a.Type},
+		hcl.TraverseAttr{Name: a.Name},
+	}
+	switch k := a.IndexKey.(type) {
+	case string:
+		t = append(t, hcl.TraverseIndex{Key: cty.StringVal(k)})
+	case float64:
+		t = append(t, hcl.TraverseIndex{Key: cty.NumberIntVal(int64(k))})
+	}
+	return t
+}
+
+// instanceAddressesByID maps "<resource type>/<Fastly ID>" to the address of the managed resource instance.
+func (s *TFState) instanceAddressesByID() (map[string]instanceAddress, error) {
+	resources, err := s.Resources()
+	if err != nil {
+		return nil, err
+	}
+
+	addrs := make(map[string]instanceAddress)
+	for _, r := range resources {
+		if r.Mode() != "managed" {
+			continue
+		}
+		for _, i := range r.Instances() {
+			id, _ := i.Attributes().String("id")
+			if id == "" {
+				continue
+			}
+			addrs[r.Type()+"/"+id] = instanceAddress{
+				Type:     r.Type(),
+				Name:     r.Name(),
+				IndexKey: i.IndexKey(),
+			}
+		}
+	}
+	return addrs, nil
+}
+
+// BuildMovedBlocks compares the resource instances of the two states using their Fastly IDs,
+// and returns "moved" blocks for the instances whose address differs between them.
+// The number of generated blocks is returned along with the configuration.
+func BuildMovedBlocks(oldState, newState *TFState) ([]byte, int, error) {
+	oldAddrs, err := oldState.instanceAddressesByID()
+	if err != nil {
+		return nil, 0, err
+	}
+	newAddrs, err := newState.instanceAddressesByID()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	type move struct {
+		from, to instanceAddress
+	}
+	moves := make([]move, 0)
+	for id, to := range newAddrs {
+		from, ok := oldAddrs[id]
+		if !ok || from.String() == to.String() {
+			continue
+		}
+		moves = append(moves, move{from, to})
+	}
+
+	// Sort by the new address so that the output is reproducible
+	sort.Slice(moves, func(i, j int) bool {
+		return moves[i].to.String() < moves[j].to.String()
+	})
+
+	f := hclwrite.NewEmptyFile()
+	body := f.Body()
+	for i, m := range moves {
+		if i > 0 {
+			body.AppendNewline()
+		}
+		block := body.AppendNewBlock("moved", nil)
+		block.Body().SetAttributeTraversal("from", m.from.Traversal())
+		block.Body().SetAttributeTraversal("to", m.to.Traversal())
+	}
+
+	return f.Bytes(), len(moves), nil
+}
@@ -0,0 +1,39 @@
+package terraformify
+
+import "testing"
+
+func TestBuildMovedBlocks(t *testing.T) {
+	oldState := parseTestState(t, `{"resources": [
+		{"mode": "managed", "type": "fastly_service_vcl", "name": "service", "instances": [{"attributes": {"id": "svc"}}]},
+		{"mode": "managed", "type": "fastly_service_dictionary_items", "name": "geo", "instances": [{"index_key": "geo", "attributes": {"id": "svc/dict1"}}]},
+		{"mode": "managed", "type": "fastly_service_dictionary_items", "name": "unchanged", "instances": [{"index_key": "unchanged", "attributes": {"id": "svc/dict2"}}]}
+	]}`)
+	newState := parseTestState(t, `{"resources": [
+		{"mode": "managed", "type": "fastly_service_vcl", "name": "example_com", "instances": [{"attributes": {"id": "svc"}}]},
+		{"mode": "managed", "type": "fastly_service_dictionary_items", "name": "geo_map", "instances": [{"index_key": "Geo Map", "attributes": {"id": "svc/dict1"}}]},
+		{"mode": "managed", "type": "fastly_service_dictionary_items", "name": "unchanged", "instances": [{"index_key": "unchanged", "attributes": {"id": "svc/dict2"}}]},
+		{"mode": "managed", "type": "fastly_service_acl_entries", "name": "new", "instances": [{"index_key": "new", "attributes": {"id": "svc/acl1"}}]}
+	]}`)
+
+	result, n, err := BuildMovedBlocks(oldState, newState)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `moved {
+  from = fastly_service_dictionary_items.geo["geo"]
+  to   = fastly_service_dictionary_items.geo_map["Geo Map"]
+}
+
+moved {
+  from = fastly_service_vcl.service
+  to   = fastly_service_vcl.example_com
+}
+`
+	if n != 2 {
+		t.Errorf("expected 2 moved blocks, got %d", n)
+	}
+	if string(result) != expected {
+		t.Errorf("unexpected moved blocks:\n got: %s\nwant: %s", result, expected)
+	}
+}
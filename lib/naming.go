@@ -0,0 +1,225 @@
+package terraformify
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+const DefaultNameTemplate = "{{.Name}}"
+
+// NameTemplateParams is the data passed to the name template
+type NameTemplateParams struct {
+	// Short name of the block type such as "service", "acl", "dictionary", "dynamicsnippet" and "waf"
+	Type string
+	// Terraform resource type such as "fastly_service_dictionary_items"
+	ResourceType string
+	// Name of the resource in Fastly
+	Name string
+	// Fastly ID of the resource
+	ID string
+}
+
+// Namer assigns valid and unique Terraform resource names to TFBlockProps
+type Namer struct {
+	tmpl *template.Template
+	// Resource names in use, keyed by resource type and then by name. The value identifies the owner.
+	used map[string]map[string]string
+}
+
+func NewNamer(nameTemplate string) (*Namer, error) {
+	if nameTemplate == "" {
+		nameTemplate = DefaultNameTemplate
+	}
+	t, err := template.New("name").Option("missingkey=error").Parse(nameTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("naming: invalid name template: %w", err)
+	}
+
+	return &Namer{
+		tmpl: t,
+		used: make(map[string]map[string]string),
+	}, nil
+}
+
+// Assign sets resource names to the props.
+// If two props end up with the same name, the one whose rendered name is already a valid identifier keeps the name,
+// and the others get numbered suffixes in the order of their Fastly IDs, not their names.
+// The suffixes skip the valid names of the other props, which are taken first.
+// A resource named like an existing one thus never takes the name of the one with the valid name.
+func (n *Namer) Assign(props ...TFBlockProp) error {
+	type candidate struct {
+		prop TFBlockProp
+		name string
+		// The rendered name is used as is
+		exact bool
+	}
+	candidates := make([]candidate, 0, len(props))
+	for _, prop := range props {
+		name, exact, err := n.render(prop)
+		if err != nil {
+			return err
+		}
+		candidates = append(candidates, candidate{prop, name, exact})
+
+		// Release the name previously assigned to the prop
+		owner := ownerKey(prop)
+		for name, o := range n.used[prop.GetType()] {
+			if o == owner {
+				delete(n.used[prop.GetType()], name)
+			}
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.name != b.name {
+			return a.name < b.name
+		}
+		if a.exact != b.exact {
+			return a.exact
+		}
+		return a.prop.GetID() < b.prop.GetID()
+	})
+
+	// Valid names are taken first, so that no numbered suffix takes the name that another prop has as is,
+	// e.g. geo_map_2 for "Geo Map" when a dictionary is named geo_map_2
+	assigned := make([]bool, len(candidates))
+	for i, c := range candidates {
+		t := c.prop.GetType()
+		if n.used[t] == nil {
+			n.used[t] = make(map[string]string)
+		}
+		if _, ok := n.used[t][c.name]; !c.exact || ok {
+			continue
+		}
+		n.used[t][c.name] = ownerKey(c.prop)
+		c.prop.SetNormalizedName(c.name)
+		assigned[i] = true
+	}
+
+	for i, c := range candidates {
+		if assigned[i] {
+			continue
+		}
+		t := c.prop.GetType()
+		name := c.name
+		for i := 2; ; i++ {
+			if _, ok := n.used[t][name]; !ok {
+				break
+			}
+			name = c.name + "_" + strconv.Itoa(i)
+		}
+		n.used[t][name] = ownerKey(c.prop)
+		c.prop.SetNormalizedName(name)
+	}
+
+	return nil
+}
+
+// Reserve marks the resource name as in use, e.g. by the existing configuration, so that no prop is assigned it
+func (n *Namer) Reserve(resourceType, name string) {
+	if n.used[resourceType] == nil {
+		n.used[resourceType] = make(map[string]string)
+	}
+	if _, ok := n.used[resourceType][name]; !ok {
+		n.used[resourceType][name] = "reserved"
+	}
+}
+
+// Reserved returns true if the resource name has been reserved
+func (n *Namer) Reserved(resourceType, name string) bool {
+	return n.used[resourceType][name] == "reserved"
+}
+
+// render returns the resource name of the prop, and whether the template rendered it as a valid name with no conversion
+func (n *Namer) render(prop TFBlockProp) (string, bool, error) {
+	params := NameTemplateParams{
+		Type:         blockTypeOf(prop),
+		ResourceType: prop.GetType(),
+		Name:         prop.GetName(),
+		ID:           prop.GetID(),
+	}
+
+	var b bytes.Buffer
+	if err := n.tmpl.Execute(&b, params); err != nil {
+		return "", false, fmt.Errorf("naming: failed to render the name of %s: %w", prop.GetType(), err)
+	}
+
+	name := toResourceName(b.String())
+	if name == "" {
+		name = params.Type
+	}
+	return name, name == b.String(), nil
+}
+
+func ownerKey(prop TFBlockProp) string {
+	return prop.GetType() + "/" + prop.GetID()
+}
+
+func blockTypeOf(prop TFBlockProp) string {
+	switch prop.(type) {
+	case *VCLServiceResourceProp:
+		return "service"
+	case *WAFResourceProp:
+		return "waf"
+	case *ACLResourceProp:
+		return "acl"
+	case *DictionaryResourceProp:
+		return "dictionary"
+	case *DynamicSnippetResourceProp:
+		return "dynamicsnippet"
+	case *TLSSubscriptionResourceProp:
+		return "tls_subscription"
+	case *TLSCertificateResourceProp:
+		return "tls_certificate"
+	case *TLSPrivateKeyResourceProp:
+		return "tls_private_key"
+	case *TLSActivationResourceProp:
+		return "tls_activation"
+	case *TLSPlatformCertificateResourceProp:
+		return "tls_platform_certificate"
+	case *KVStoreResourceProp:
+		return "kvstore"
+	case *ConfigStoreResourceProp:
+		return "configstore"
+	case *ConfigStoreEntriesResourceProp:
+		return "configstore_entries"
+	case *SecretStoreResourceProp:
+		return "secretstore"
+	case *UserResourceProp:
+		return "user"
+	case *ServiceAuthorizationResourceProp:
+		return "service_authorization"
+	case *CustomDashboardResourceProp:
+		return "custom_dashboard"
+	default:
+		return strings.TrimPrefix(prop.GetType(), "fastly_")
+	}
+}
+
+// toResourceName converts the string into a valid Terraform resource name.
+// It behaves the same as normalize() for names that are already valid.
+func toResourceName(name string) string {
+	name = normalize(name)
+
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9', r == '_', r == '-':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	name = b.String()
+
+	// A name must begin with a letter or underscore
+	if name != "" && !isValidResourceName(name) {
+		name = "_" + name
+	}
+	return name
+}
@@ -0,0 +1,138 @@
+package terraformify
+
+import "testing"
+
+func TestNamerAssign(t *testing.T) {
+	serviceProp := NewVCLServiceResourceProp("svc", "service", 0)
+
+	testCases := []struct {
+		template string
+		props    []TFBlockProp
+		expected []string
+	}{
+		{
+			template: "",
+			props: []TFBlockProp{
+				NewDictionaryResourceProp("d3", "geo_map", serviceProp),
+				NewDictionaryResourceProp("d2", "Geo Map", serviceProp),
+				NewDictionaryResourceProp("d1", "geo.map", serviceProp),
+				NewACLResourceProp("a1", "geo_map", serviceProp),
+			},
+			// The valid name is kept, and the other collisions are resolved in the order of the Fastly ID, regardless of the input order
+			expected: []string{"geo_map", "geo_map_3", "geo_map_2", "geo_map"},
+		},
+		{
+			template: "",
+			props: []TFBlockProp{
+				NewDictionaryResourceProp("d1", "geo_map", serviceProp),
+				NewDictionaryResourceProp("d2", "Geo Map", serviceProp),
+				NewDictionaryResourceProp("d3", "geo_map_2", serviceProp),
+			},
+			// The numbered suffix skips the name that another dictionary has as is
+			expected: []string{"geo_map", "geo_map_3", "geo_map_2"},
+		},
+		{
+			template: "",
+			props: []TFBlockProp{
+				NewDictionaryResourceProp("d1", "café/menü", serviceProp),
+				NewDictionaryResourceProp("d2", "2nd-table", serviceProp),
+				NewDynamicSnippetResourceProp("s1", "!!!", serviceProp),
+			},
+			expected: []string{"caf__men_", "_2nd-table", "___"},
+		},
+		{
+			template: "{{.Type}}_{{.Name}}",
+			props: []TFBlockProp{
+				NewDictionaryResourceProp("d1", "Redirects", serviceProp),
+				NewWAFResourceProp("w1", serviceProp),
+			},
+			expected: []string{"dictionary_redirects", "waf_waf"},
+		},
+	}
+
+	for _, tt := range testCases {
+		namer, err := NewNamer(tt.template)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := namer.Assign(tt.props...); err != nil {
+			t.Fatal(err)
+		}
+		for i, prop := range tt.props {
+			if got := prop.GetNormalizedName(); got != tt.expected[i] {
+				t.Errorf("%s %q: expected %q, got %q", prop.GetType(), prop.GetName(), tt.expected[i], got)
+			}
+			if !isValidResourceName(prop.GetNormalizedName()) {
+				t.Errorf("%q is not a valid resource name", prop.GetNormalizedName())
+			}
+		}
+	}
+}
+
+func TestNamerAssignAdded(t *testing.T) {
+	serviceProp := NewVCLServiceResourceProp("svc", "service", 0)
+	assign := func(props ...TFBlockProp) map[string]string {
+		namer, err := NewNamer("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := namer.Assign(props...); err != nil {
+			t.Fatal(err)
+		}
+		names := make(map[string]string, len(props))
+		for _, prop := range props {
+			names[prop.GetID()] = prop.GetNormalizedName()
+		}
+		return names
+	}
+
+	before := assign(
+		NewDictionaryResourceProp("d5", "geo_map", serviceProp),
+		NewDictionaryResourceProp("d6", "Geo Map", serviceProp),
+	)
+	// Dictionaries with colliding names are added later
+	after := assign(
+		NewDictionaryResourceProp("d8", "GEO MAP", serviceProp),
+		NewDictionaryResourceProp("d5", "geo_map", serviceProp),
+		NewDictionaryResourceProp("d6", "Geo Map", serviceProp),
+		NewDictionaryResourceProp("d7", "geo.map", serviceProp),
+	)
+	for _, id := range []string{"d5", "d6"} {
+		if before[id] != after[id] {
+			t.Errorf("%s is renamed from %s to %s", id, before[id], after[id])
+		}
+	}
+	if after["d5"] != "geo_map" || after["d6"] != "geo_map_2" || after["d7"] != "geo_map_3" || after["d8"] != "geo_map_4" {
+		t.Errorf("unexpected names: %v", after)
+	}
+}
+
+func TestNamerReserve(t *testing.T) {
+	namer, err := NewNamer("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Resources of the existing configuration
+	namer.Reserve("fastly_service_vcl", "service")
+	namer.Reserve("fastly_service_vcl", "example")
+
+	serviceProp := NewVCLServiceResourceProp("svc", "service", 0)
+	if err := namer.Assign(serviceProp); err != nil {
+		t.Fatal(err)
+	}
+	if got := serviceProp.GetNormalizedName(); got != "service_2" {
+		t.Errorf("got %q, want %q", got, "service_2")
+	}
+
+	// Renaming releases the placeholder name but not the reserved ones
+	serviceProp.Name = "example"
+	if err := namer.Assign(serviceProp); err != nil {
+		t.Fatal(err)
+	}
+	if got := serviceProp.GetNormalizedName(); got != "example_2" {
+		t.Errorf("got %q, want %q", got, "example_2")
+	}
+	if !namer.Reserved("fastly_service_vcl", "service") || namer.Reserved("fastly_service_vcl", "service_2") {
+		t.Error("unexpected reserved names")
+	}
+}
@@ -12,6 +12,7 @@ type TFBlockProp interface {
 	GetIDforTFImport() string
 	GetName() string
 	GetNormalizedName() string
+	SetNormalizedName(name string)
 	GetRef() string
 }
 
@@ -19,6 +20,7 @@ type VCLServiceResourceProp struct {
 	ID            string
 	Name          string
 	TargetVersion int
+	resourceName  string
 }
 
 func NewVCLServiceResourceProp(id, name string, targetversion int) *VCLServiceResourceProp {
@@ -44,22 +46,29 @@ func (v *VCLServiceResourceProp) GetName() string {
 	return v.Name
 }
 func (v *VCLServiceResourceProp) GetNormalizedName() string {
+	if v.resourceName != "" {
+		return v.resourceName
+	}
 	// Check if the name can be used as a Terraform resource name
 	// If not, falling back to the default resource name
-	name := normalize(v.GetName())
+	name := toResourceName(v.GetName())
 	if !isValidResourceName(name) {
 		name = "service"
 	}
 	return name
 }
+func (v *VCLServiceResourceProp) SetNormalizedName(name string) {
+	v.resourceName = name
+}
 func (v *VCLServiceResourceProp) GetRef() string {
 	return v.GetType() + "." + v.GetNormalizedName()
 }
 
 type WAFResourceProp struct {
 	*VCLServiceResourceProp
-	ID   string
-	Name string
+	ID           string
+	Name         string
+	resourceName string
 }
 
 func NewWAFResourceProp(id string, sr *VCLServiceResourceProp) *WAFResourceProp {
@@ -82,7 +91,13 @@ func (w *WAFResourceProp) GetName() string {
 	return w.Name
 }
 func (w *WAFResourceProp) GetNormalizedName() string {
-	return normalize(w.GetName())
+	if w.resourceName != "" {
+		return w.resourceName
+	}
+	return toResourceName(w.GetName())
+}
+func (w *WAFResourceProp) SetNormalizedName(name string) {
+	w.resourceName = name
 }
 func (w *WAFResourceProp) GetRef() string {
 	return w.GetType() + "." + w.GetNormalizedName()
@@ -90,9 +105,10 @@ func (w *WAFResourceProp) GetRef() string {
 
 type ACLResourceProp struct {
 	*VCLServiceResourceProp
-	ID   string
-	Name string
-	No   int
+	ID           string
+	Name         string
+	No           int
+	resourceName string
 }
 
 func NewACLResourceProp(id, name string, sr *VCLServiceResourceProp) *ACLResourceProp {
@@ -115,7 +131,13 @@ func (a *ACLResourceProp) GetName() string {
 	return a.Name
 }
 func (a *ACLResourceProp) GetNormalizedName() string {
-	return normalize(a.Name)
+	if a.resourceName != "" {
+		return a.resourceName
+	}
+	return toResourceName(a.Name)
+}
+func (a *ACLResourceProp) SetNormalizedName(name string) {
+	a.resourceName = name
 }
 func (a *ACLResourceProp) GetRef() string {
 	return a.GetType() + "." + a.GetNormalizedName()
@@ -123,8 +145,9 @@ func (a *ACLResourceProp) GetRef() string {
 
 type DictionaryResourceProp struct {
 	*VCLServiceResourceProp
-	ID   string
-	Name string
+	ID           string
+	Name         string
+	resourceName string
 }
 
 func NewDictionaryResourceProp(id, name string, sr *VCLServiceResourceProp) *DictionaryResourceProp {
@@ -147,7 +170,13 @@ func (d *DictionaryResourceProp) GetName() string {
 	return d.Name
 }
 func (d *DictionaryResourceProp) GetNormalizedName() string {
-	return normalize(d.GetName())
+	if d.resourceName != "" {
+		return d.resourceName
+	}
+	return toResourceName(d.GetName())
+}
+func (d *DictionaryResourceProp) SetNormalizedName(name string) {
+	d.resourceName = name
 }
 func (d *DictionaryResourceProp) GetRef() string {
 	return d.GetType() + "." + d.GetNormalizedName()
@@ -155,8 +184,9 @@ func (d *DictionaryResourceProp) GetRef() string {
 
 type DynamicSnippetResourceProp struct {
 	*VCLServiceResourceProp
-	ID   string
-	Name string
+	ID           string
+	Name         string
+	resourceName string
 }
 
 func NewDynamicSnippetResourceProp(id, name string, sr *VCLServiceResourceProp) *DynamicSnippetResourceProp {
@@ -179,12 +209,480 @@ func (ds *DynamicSnippetResourceProp) GetName() string {
 	return ds.Name
 }
 func (ds *DynamicSnippetResourceProp) GetNormalizedName() string {
-	return normalize(ds.GetName())
+	if ds.resourceName != "" {
+		return ds.resourceName
+	}
+	return toResourceName(ds.GetName())
+}
+func (ds *DynamicSnippetResourceProp) SetNormalizedName(name string) {
+	ds.resourceName = name
 }
 func (ds *DynamicSnippetResourceProp) GetRef() string {
 	return ds.GetType() + "." + ds.GetNormalizedName()
 }
 
+type TLSSubscriptionResourceProp struct {
+	*VCLServiceResourceProp
+	ID           string
+	Name         string
+	resourceName string
+}
+
+func NewTLSSubscriptionResourceProp(id, name string, sr *VCLServiceResourceProp) *TLSSubscriptionResourceProp {
+	return &TLSSubscriptionResourceProp{
+		VCLServiceResourceProp: sr,
+		ID:                     id,
+		Name:                   name,
+	}
+}
+func (t *TLSSubscriptionResourceProp) GetType() string {
+	return "fastly_tls_subscription"
+}
+func (t *TLSSubscriptionResourceProp) GetID() string {
+	return t.ID
+}
+func (t *TLSSubscriptionResourceProp) GetIDforTFImport() string {
+	return t.GetID()
+}
+func (t *TLSSubscriptionResourceProp) GetName() string {
+	return t.Name
+}
+func (t *TLSSubscriptionResourceProp) GetNormalizedName() string {
+	if t.resourceName != "" {
+		return t.resourceName
+	}
+	return toResourceName(t.GetName())
+}
+func (t *TLSSubscriptionResourceProp) SetNormalizedName(name string) {
+	t.resourceName = name
+}
+func (t *TLSSubscriptionResourceProp) GetRef() string {
+	return t.GetType() + "." + t.GetNormalizedName()
+}
+
+type TLSCertificateResourceProp struct {
+	*VCLServiceResourceProp
+	ID           string
+	Name         string
+	resourceName string
+}
+
+func NewTLSCertificateResourceProp(id, name string, sr *VCLServiceResourceProp) *TLSCertificateResourceProp {
+	return &TLSCertificateResourceProp{
+		VCLServiceResourceProp: sr,
+		ID:                     id,
+		Name:                   name,
+	}
+}
+func (t *TLSCertificateResourceProp) GetType() string {
+	return "fastly_tls_certificate"
+}
+func (t *TLSCertificateResourceProp) GetID() string {
+	return t.ID
+}
+func (t *TLSCertificateResourceProp) GetIDforTFImport() string {
+	return t.GetID()
+}
+func (t *TLSCertificateResourceProp) GetName() string {
+	return t.Name
+}
+func (t *TLSCertificateResourceProp) GetNormalizedName() string {
+	if t.resourceName != "" {
+		return t.resourceName
+	}
+	return toResourceName(t.GetName())
+}
+func (t *TLSCertificateResourceProp) SetNormalizedName(name string) {
+	t.resourceName = name
+}
+func (t *TLSCertificateResourceProp) GetRef() string {
+	return t.GetType() + "." + t.GetNormalizedName()
+}
+
+type TLSPrivateKeyResourceProp struct {
+	*VCLServiceResourceProp
+	ID           string
+	Name         string
+	resourceName string
+}
+
+func NewTLSPrivateKeyResourceProp(id, name string, sr *VCLServiceResourceProp) *TLSPrivateKeyResourceProp {
+	return &TLSPrivateKeyResourceProp{
+		VCLServiceResourceProp: sr,
+		ID:                     id,
+		Name:                   name,
+	}
+}
+func (t *TLSPrivateKeyResourceProp) GetType() string {
+	return "fastly_tls_private_key"
+}
+func (t *TLSPrivateKeyResourceProp) GetID() string {
+	return t.ID
+}
+func (t *TLSPrivateKeyResourceProp) GetIDforTFImport() string {
+	return t.GetID()
+}
+func (t *TLSPrivateKeyResourceProp) GetName() string {
+	return t.Name
+}
+func (t *TLSPrivateKeyResourceProp) GetNormalizedName() string {
+	if t.resourceName != "" {
+		return t.resourceName
+	}
+	return toResourceName(t.GetName())
+}
+func (t *TLSPrivateKeyResourceProp) SetNormalizedName(name string) {
+	t.resourceName = name
+}
+func (t *TLSPrivateKeyResourceProp) GetRef() string {
+	return t.GetType() + "." + t.GetNormalizedName()
+}
+
+type TLSActivationResourceProp struct {
+	*VCLServiceResourceProp
+	ID           string
+	Name         string
+	resourceName string
+}
+
+func NewTLSActivationResourceProp(id, domain string, sr *VCLServiceResourceProp) *TLSActivationResourceProp {
+	return &TLSActivationResourceProp{
+		VCLServiceResourceProp: sr,
+		ID:                     id,
+		Name:                   domain,
+	}
+}
+func (t *TLSActivationResourceProp) GetType() string {
+	return "fastly_tls_activation"
+}
+func (t *TLSActivationResourceProp) GetID() string {
+	return t.ID
+}
+func (t *TLSActivationResourceProp) GetIDforTFImport() string {
+	return t.GetID()
+}
+func (t *TLSActivationResourceProp) GetName() string {
+	return t.Name
+}
+func (t *TLSActivationResourceProp) GetNormalizedName() string {
+	if t.resourceName != "" {
+		return t.resourceName
+	}
+	return toResourceName(t.GetName())
+}
+func (t *TLSActivationResourceProp) SetNormalizedName(name string) {
+	t.resourceName = name
+}
+func (t *TLSActivationResourceProp) GetRef() string {
+	return t.GetType() + "." + t.GetNormalizedName()
+}
+
+type TLSPlatformCertificateResourceProp struct {
+	*VCLServiceResourceProp
+	ID           string
+	Name         string
+	resourceName string
+}
+
+func NewTLSPlatformCertificateResourceProp(id, name string, sr *VCLServiceResourceProp) *TLSPlatformCertificateResourceProp {
+	return &TLSPlatformCertificateResourceProp{
+		VCLServiceResourceProp: sr,
+		ID:                     id,
+		Name:                   name,
+	}
+}
+func (t *TLSPlatformCertificateResourceProp) GetType() string {
+	return "fastly_tls_platform_certificate"
+}
+func (t *TLSPlatformCertificateResourceProp) GetID() string {
+	return t.ID
+}
+func (t *TLSPlatformCertificateResourceProp) GetIDforTFImport() string {
+	return t.GetID()
+}
+func (t *TLSPlatformCertificateResourceProp) GetName() string {
+	return t.Name
+}
+func (t *TLSPlatformCertificateResourceProp) GetNormalizedName() string {
+	if t.resourceName != "" {
+		return t.resourceName
+	}
+	return toResourceName(t.GetName())
+}
+func (t *TLSPlatformCertificateResourceProp) SetNormalizedName(name string) {
+	t.resourceName = name
+}
+func (t *TLSPlatformCertificateResourceProp) GetRef() string {
+	return t.GetType() + "." + t.GetNormalizedName()
+}
+
+type KVStoreResourceProp struct {
+	*VCLServiceResourceProp
+	ID           string
+	Name         string
+	resourceName string
+}
+
+func NewKVStoreResourceProp(id, name string, sr *VCLServiceResourceProp) *KVStoreResourceProp {
+	return &KVStoreResourceProp{
+		VCLServiceResourceProp: sr,
+		ID:                     id,
+		Name:                   name,
+	}
+}
+func (k *KVStoreResourceProp) GetType() string {
+	return "fastly_kvstore"
+}
+func (k *KVStoreResourceProp) GetID() string {
+	return k.ID
+}
+func (k *KVStoreResourceProp) GetIDforTFImport() string {
+	return k.GetID()
+}
+func (k *KVStoreResourceProp) GetName() string {
+	return k.Name
+}
+func (k *KVStoreResourceProp) GetNormalizedName() string {
+	if k.resourceName != "" {
+		return k.resourceName
+	}
+	return toResourceName(k.GetName())
+}
+func (k *KVStoreResourceProp) SetNormalizedName(name string) {
+	k.resourceName = name
+}
+func (k *KVStoreResourceProp) GetRef() string {
+	return k.GetType() + "." + k.GetNormalizedName()
+}
+
+type ConfigStoreResourceProp struct {
+	*VCLServiceResourceProp
+	ID           string
+	Name         string
+	resourceName string
+}
+
+func NewConfigStoreResourceProp(id, name string, sr *VCLServiceResourceProp) *ConfigStoreResourceProp {
+	return &ConfigStoreResourceProp{
+		VCLServiceResourceProp: sr,
+		ID:                     id,
+		Name:                   name,
+	}
+}
+func (c *ConfigStoreResourceProp) GetType() string {
+	return "fastly_configstore"
+}
+func (c *ConfigStoreResourceProp) GetID() string {
+	return c.ID
+}
+func (c *ConfigStoreResourceProp) GetIDforTFImport() string {
+	return c.GetID()
+}
+func (c *ConfigStoreResourceProp) GetName() string {
+	return c.Name
+}
+func (c *ConfigStoreResourceProp) GetNormalizedName() string {
+	if c.resourceName != "" {
+		return c.resourceName
+	}
+	return toResourceName(c.GetName())
+}
+func (c *ConfigStoreResourceProp) SetNormalizedName(name string) {
+	c.resourceName = name
+}
+func (c *ConfigStoreResourceProp) GetRef() string {
+	return c.GetType() + "." + c.GetNormalizedName()
+}
+
+type ConfigStoreEntriesResourceProp struct {
+	*VCLServiceResourceProp
+	ID           string
+	Name         string
+	resourceName string
+}
+
+func NewConfigStoreEntriesResourceProp(id, name string, sr *VCLServiceResourceProp) *ConfigStoreEntriesResourceProp {
+	return &ConfigStoreEntriesResourceProp{
+		VCLServiceResourceProp: sr,
+		ID:                     id,
+		Name:                   name,
+	}
+}
+func (c *ConfigStoreEntriesResourceProp) GetType() string {
+	return "fastly_configstore_entries"
+}
+func (c *ConfigStoreEntriesResourceProp) GetID() string {
+	return c.ID
+}
+func (c *ConfigStoreEntriesResourceProp) GetIDforTFImport() string {
+	return c.GetID()
+}
+func (c *ConfigStoreEntriesResourceProp) GetName() string {
+	return c.Name
+}
+func (c *ConfigStoreEntriesResourceProp) GetNormalizedName() string {
+	if c.resourceName != "" {
+		return c.resourceName
+	}
+	return toResourceName(c.GetName())
+}
+func (c *ConfigStoreEntriesResourceProp) SetNormalizedName(name string) {
+	c.resourceName = name
+}
+func (c *ConfigStoreEntriesResourceProp) GetRef() string {
+	return c.GetType() + "." + c.GetNormalizedName()
+}
+
+type SecretStoreResourceProp struct {
+	*VCLServiceResourceProp
+	ID           string
+	Name         string
+	resourceName string
+}
+
+func NewSecretStoreResourceProp(id, name string, sr *VCLServiceResourceProp) *SecretStoreResourceProp {
+	return &SecretStoreResourceProp{
+		VCLServiceResourceProp: sr,
+		ID:                     id,
+		Name:                   name,
+	}
+}
+func (s *SecretStoreResourceProp) GetType() string {
+	return "fastly_secretstore"
+}
+func (s *SecretStoreResourceProp) GetID() string {
+	return s.ID
+}
+func (s *SecretStoreResourceProp) GetIDforTFImport() string {
+	return s.GetID()
+}
+func (s *SecretStoreResourceProp) GetName() string {
+	return s.Name
+}
+func (s *SecretStoreResourceProp) GetNormalizedName() string {
+	if s.resourceName != "" {
+		return s.resourceName
+	}
+	return toResourceName(s.GetName())
+}
+func (s *SecretStoreResourceProp) SetNormalizedName(name string) {
+	s.resourceName = name
+}
+func (s *SecretStoreResourceProp) GetRef() string {
+	return s.GetType() + "." + s.GetNormalizedName()
+}
+
+type UserResourceProp struct {
+	ID           string
+	Name         string
+	resourceName string
+}
+
+func NewUserResourceProp(id, name string) *UserResourceProp {
+	return &UserResourceProp{
+		ID:   id,
+		Name: name,
+	}
+}
+func (u *UserResourceProp) GetType() string {
+	return "fastly_user"
+}
+func (u *UserResourceProp) GetID() string {
+	return u.ID
+}
+func (u *UserResourceProp) GetIDforTFImport() string {
+	return u.GetID()
+}
+func (u *UserResourceProp) GetName() string {
+	return u.Name
+}
+func (u *UserResourceProp) GetNormalizedName() string {
+	if u.resourceName != "" {
+		return u.resourceName
+	}
+	return toResourceName(u.GetName())
+}
+func (u *UserResourceProp) SetNormalizedName(name string) {
+	u.resourceName = name
+}
+func (u *UserResourceProp) GetRef() string {
+	return u.GetType() + "." + u.GetNormalizedName()
+}
+
+type ServiceAuthorizationResourceProp struct {
+	ID           string
+	Name         string
+	resourceName string
+}
+
+func NewServiceAuthorizationResourceProp(id, name string) *ServiceAuthorizationResourceProp {
+	return &ServiceAuthorizationResourceProp{
+		ID:   id,
+		Name: name,
+	}
+}
+func (sa *ServiceAuthorizationResourceProp) GetType() string {
+	return "fastly_service_authorization"
+}
+func (sa *ServiceAuthorizationResourceProp) GetID() string {
+	return sa.ID
+}
+func (sa *ServiceAuthorizationResourceProp) GetIDforTFImport() string {
+	return sa.GetID()
+}
+func (sa *ServiceAuthorizationResourceProp) GetName() string {
+	return sa.Name
+}
+func (sa *ServiceAuthorizationResourceProp) GetNormalizedName() string {
+	if sa.resourceName != "" {
+		return sa.resourceName
+	}
+	return toResourceName(sa.GetName())
+}
+func (sa *ServiceAuthorizationResourceProp) SetNormalizedName(name string) {
+	sa.resourceName = name
+}
+func (sa *ServiceAuthorizationResourceProp) GetRef() string {
+	return sa.GetType() + "." + sa.GetNormalizedName()
+}
+
+type CustomDashboardResourceProp struct {
+	ID           string
+	Name         string
+	resourceName string
+}
+
+func NewCustomDashboardResourceProp(id, name string) *CustomDashboardResourceProp {
+	return &CustomDashboardResourceProp{
+		ID:   id,
+		Name: name,
+	}
+}
+func (cd *CustomDashboardResourceProp) GetType() string {
+	return "fastly_custom_dashboard"
+}
+func (cd *CustomDashboardResourceProp) GetID() string {
+	return cd.ID
+}
+func (cd *CustomDashboardResourceProp) GetIDforTFImport() string {
+	return cd.GetID()
+}
+func (cd *CustomDashboardResourceProp) GetName() string {
+	return cd.Name
+}
+func (cd *CustomDashboardResourceProp) GetNormalizedName() string {
+	if cd.resourceName != "" {
+		return cd.resourceName
+	}
+	return toResourceName(cd.GetName())
+}
+func (cd *CustomDashboardResourceProp) SetNormalizedName(name string) {
+	cd.resourceName = name
+}
+func (cd *CustomDashboardResourceProp) GetRef() string {
+	return cd.GetType() + "." + cd.GetNormalizedName()
+}
+
 func normalize(name string) string {
 	name = strings.ToLower(name)
 	name = strings.ReplaceAll(name, ".", "_")
@@ -193,15 +691,10 @@ func normalize(name string) string {
 	return strings.ReplaceAll(name, " ", "_")
 }
 
+var resourceNameRegexp = regexp.MustCompile(`^[A-Za-z_][0-9A-Za-z_\-]*$`)
+
 func isValidResourceName(name string) bool {
 	// Validate if the string can be used as a Terraform resource name
-	// - No check is necessary for "fastly_service_waf_configuration" because the name is fixed to "waf"
-	// - No check is necessary for the following resources because invalid names are not accepted at Fastly
-	//	- "fastly_service_acl_entries"
-	//	- "fastly_service_dictionary_items"
-	//	- "fastly_service_dynamic_snippet_content"
-
 	// A TF resource names begin with a letter or underscore and may contain only letters, digits, underscores, and dashes
-	// Spaces and dots are allowed here since they are replaced with underscores in TFBlockProp.GetNormalizedName()
-	return regexp.MustCompile(`^[A-Za-z_][0-9A-Za-z_.\-\\s]*$`).MatchString(name)
+	return resourceNameRegexp.MatchString(name)
 }
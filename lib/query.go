@@ -0,0 +1,207 @@
+package terraformify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/itchyny/gojq"
+	"gopkg.in/yaml.v2"
+)
+
+// QueryShortcut is a named jq query for the questions that are asked often
+type QueryShortcut struct {
+	Description string
+	Query       string
+	// Columns of the table, in order
+	Columns []string
+}
+
+// The services in the state, with $s bound to the attributes of each service
+const queryServices = `.resources[] | select(.mode == "managed" and .type == "fastly_service_vcl") | .instances[] | .attributes as $s`
+
+var QueryShortcuts = map[string]QueryShortcut{
+	"backends": {
+		Description: "Backends of the services",
+		Query:       `[` + queryServices + ` | ($s.backend // [])[] | {service: $s.name, service_id: $s.id, name, address, port, use_ssl}]`,
+		Columns:     []string{"service", "service_id", "name", "address", "port", "use_ssl"},
+	},
+	"logging": {
+		Description: "Logging endpoints of the services",
+		Query: `[` + queryServices + ` | $s | to_entries[] | select(.key | startswith("logging_")) | .key as $t | (.value // [])[] |
+			{service: $s.name, service_id: $s.id, type: ($t | ltrimstr("logging_")), name, endpoint: (` + loggingDestinationQuery() + `)}]`,
+		Columns: []string{"service", "service_id", "type", "name", "endpoint"},
+	},
+	"secrets": {
+		Description: "Attributes that hold credentials, without the values",
+		Query: `[` + queryServices + ` | $s | paths(type == "string" and . != "") as $p |
+			select($p[-1] | tostring | test("` + credentialAttributesRegexp() + `")) |
+			{service: $s.name, service_id: $s.id, block: $p[0], name: (if ($p | length) > 2 then $s | getpath($p[0:2]) | .name else null end), attribute: $p[-1]}]`,
+		Columns: []string{"service", "service_id", "block", "name", "attribute"},
+	},
+	"snippets": {
+		Description: "VCL snippets of the services, including dynamic ones",
+		Query: `[` + queryServices + ` |
+			(($s.snippet // [])[] | {service: $s.name, service_id: $s.id, name, type, priority, dynamic: false}),
+			(($s.dynamicsnippet // [])[] | {service: $s.name, service_id: $s.id, name, type, priority, dynamic: true})]`,
+		Columns: []string{"service", "service_id", "name", "type", "priority", "dynamic"},
+	},
+}
+
+// credentialAttributesRegexp returns the regular expression for jq that matches the names in credentialAttributes
+func credentialAttributesRegexp() string {
+	return "^(" + strings.Join(credentialAttributes, "|") + ")$"
+}
+
+// loggingDestinationQuery returns the jq expression for the first attribute of loggingDestinationKeys that is not empty
+func loggingDestinationQuery() string {
+	exprs := make([]string, len(loggingDestinationKeys))
+	for i, k := range loggingDestinationKeys {
+		exprs[i] = fmt.Sprintf(`(.%s | select(. != ""))`, k)
+	}
+	return strings.Join(exprs, " // ")
+}
+
+// QueryShortcutNames returns the names of the shortcuts in order
+func QueryShortcutNames() []string {
+	names := make([]string, 0, len(QueryShortcuts))
+	for name := range QueryShortcuts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// QueryAll runs the jq query against the state and returns all the results
+func (s *TFState) QueryAll(query string) ([]interface{}, error) {
+	jq, err := gojq.Parse(query)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query: %w", err)
+	}
+	var results []interface{}
+	iter := jq.Run(s.Value)
+	for {
+		v, ok := iter.Next()
+		if !ok {
+			break
+		}
+		if err, ok := v.(error); ok {
+			return nil, err
+		}
+		results = append(results, v)
+	}
+	return results, nil
+}
+
+func ValidateQueryFormat(format string) error {
+	switch format {
+	case "json", "yaml", "table":
+		return nil
+	default:
+		return fmt.Errorf("unknown format %q: must be one of json, yaml, table", format)
+	}
+}
+
+// FormatQueryResults renders the results of a query.
+// Each result is a document in json and yaml. In table, the elements of array results are the rows,
+// and the columns are the keys of the objects unless they are given.
+func FormatQueryResults(results []interface{}, format string, columns []string) ([]byte, error) {
+	var buf bytes.Buffer
+	switch format {
+	case "json":
+		enc := json.NewEncoder(&buf)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		for _, r := range results {
+			if err := enc.Encode(r); err != nil {
+				return nil, err
+			}
+		}
+	case "yaml":
+		for i, r := range results {
+			if i > 0 {
+				buf.WriteString("---\n")
+			}
+			b, err := yaml.Marshal(r)
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(b)
+		}
+	case "table":
+		var table bytes.Buffer
+		writeQueryTable(&table, results, columns)
+		// tabwriter pads the empty cells at the end of the lines
+		lines := strings.Split(table.String(), "\n")
+		for i := range lines {
+			lines[i] = strings.TrimRight(lines[i], " ")
+		}
+		buf.WriteString(strings.Join(lines, "\n"))
+	default:
+		return nil, ValidateQueryFormat(format)
+	}
+	return buf.Bytes(), nil
+}
+
+func writeQueryTable(buf *bytes.Buffer, results []interface{}, columns []string) {
+	var rows []interface{}
+	for _, r := range results {
+		if list, ok := r.([]interface{}); ok {
+			rows = append(rows, list...)
+		} else {
+			rows = append(rows, r)
+		}
+	}
+
+	if len(columns) == 0 {
+		seen := make(map[string]bool)
+		for _, r := range rows {
+			m, ok := r.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			for k := range m {
+				if !seen[k] {
+					seen[k] = true
+					columns = append(columns, k)
+				}
+			}
+		}
+		sort.Strings(columns)
+	}
+
+	w := tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+	if len(columns) == 0 {
+		// Scalar results
+		for _, r := range rows {
+			fmt.Fprintln(w, queryTableCell(r))
+		}
+		return
+	}
+	fmt.Fprintln(w, strings.ToUpper(strings.Join(columns, "\t")))
+	for _, r := range rows {
+		m, _ := r.(map[string]interface{})
+		cells := make([]string, len(columns))
+		for i, c := range columns {
+			cells[i] = queryTableCell(m[c])
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+}
+
+// queryTableCell renders the value in a single line
+func queryTableCell(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.ReplaceAll(v, "\n", `\n`)
+	default:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+}
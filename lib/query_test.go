@@ -0,0 +1,118 @@
+package terraformify
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestQueryShortcuts(t *testing.T) {
+	tfstate, err := LoadTFState("../testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range QueryShortcutNames() {
+		results, err := tfstate.QueryAll(QueryShortcuts[name].Query)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(results) != 1 {
+			t.Fatalf("%s: got %d results, want 1", name, len(results))
+		}
+		if _, ok := results[0].([]interface{}); !ok {
+			t.Errorf("%s: got %T, want an array", name, results[0])
+		}
+	}
+}
+
+func TestFormatQueryResults(t *testing.T) {
+	results := []interface{}{
+		[]interface{}{
+			map[string]interface{}{"name": "origin", "port": float64(443), "use_ssl": true},
+			map[string]interface{}{"name": "R&D", "address": "example.com"},
+		},
+	}
+
+	testCases := []struct {
+		format   string
+		columns  []string
+		expected string
+	}{
+		{
+			format: "json",
+			expected: `[
+  {
+    "name": "origin",
+    "port": 443,
+    "use_ssl": true
+  },
+  {
+    "address": "example.com",
+    "name": "R&D"
+  }
+]
+`,
+		},
+		{
+			format: "yaml",
+			expected: `- name: origin
+  port: 443
+  use_ssl: true
+- address: example.com
+  name: R&D
+`,
+		},
+		{
+			format: "table",
+			expected: `ADDRESS      NAME    PORT  USE_SSL
+             origin  443   true
+example.com  R&D
+`,
+		},
+		{
+			format:  "table",
+			columns: []string{"name", "address"},
+			expected: `NAME    ADDRESS
+origin
+R&D     example.com
+`,
+		},
+	}
+	for _, tt := range testCases {
+		got, err := FormatQueryResults(results, tt.format, tt.columns)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tt.expected {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.format, got, tt.expected)
+		}
+	}
+
+	if _, err := FormatQueryResults(results, "csv", nil); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestQuerySecrets(t *testing.T) {
+	tfstate := parseTestState(t, `{"resources": [{"mode": "managed", "type": "fastly_service_vcl", "name": "service", "instances": [{"attributes": {
+		"id": "svc", "name": "example",
+		"backend": [{"name": "origin", "address": "example.com", "ssl_client_key": "KEY", "ssl_client_cert": "CERT"}],
+		"logging_https": [{"name": "weblogs", "url": "https://example.com", "tls_client_key": "KEY", "format": "%h"}],
+		"logging_s3": [{"name": "archive", "s3_access_key": "AK", "s3_secret_key": "", "token": "T"}]
+	}}]}]}`)
+
+	results, err := tfstate.QueryAll(QueryShortcuts["secrets"].Query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, r := range results[0].([]interface{}) {
+		m := r.(map[string]interface{})
+		got = append(got, m["name"].(string)+"."+m["attribute"].(string))
+	}
+	// Empty values are not credentials in use
+	want := []string{"origin.ssl_client_key", "weblogs.tls_client_key", "archive.s3_access_key", "archive.token"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,223 @@
+package terraformify
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// StateResource is a resource in the state.
+// It is a view of the decoded state, so that the changes made through it are reflected in the state.
+type StateResource struct {
+	m map[string]interface{}
+}
+
+func (r StateResource) Mode() string {
+	s, _ := r.m["mode"].(string)
+	return s
+}
+
+func (r StateResource) Type() string {
+	s, _ := r.m["type"].(string)
+	return s
+}
+
+func (r StateResource) Name() string {
+	s, _ := r.m["name"].(string)
+	return s
+}
+
+// Address returns the address of the resource, e.g. fastly_service_vcl.service
+func (r StateResource) Address() string {
+	return r.Type() + "." + r.Name()
+}
+
+func (r StateResource) setName(name string) {
+	r.m["name"] = name
+}
+
+// Instances returns the instances of the resource. for_each resources have an instance for each key.
+func (r StateResource) Instances() []StateInstance {
+	list, _ := r.m["instances"].([]interface{})
+	instances := make([]StateInstance, 0, len(list))
+	for _, i := range list {
+		m, ok := i.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		instances = append(instances, StateInstance{resource: r, m: m})
+	}
+	return instances
+}
+
+// Instance returns the only instance of the resource, which is the case right after "terraform import"
+func (r StateResource) Instance() (StateInstance, error) {
+	instances := r.Instances()
+	if len(instances) != 1 {
+		return StateInstance{}, fmt.Errorf("tfstate: %s has %d instances, expected 1", r.Address(), len(instances))
+	}
+	return instances[0], nil
+}
+
+// StateInstance is an instance of a resource in the state
+type StateInstance struct {
+	resource StateResource
+	m        map[string]interface{}
+}
+
+// IndexKey returns the key of the instance: a string for for_each, a number for count, or nil
+func (i StateInstance) IndexKey() interface{} {
+	return i.m["index_key"]
+}
+
+func (i StateInstance) SetIndexKey(key string) {
+	i.m["index_key"] = key
+}
+
+// Address returns the address of the instance, e.g. fastly_service_dictionary_items.geo["geo"]
+func (i StateInstance) Address() string {
+	switch k := i.IndexKey().(type) {
+	case string:
+		return fmt.Sprintf("%s[%s]", i.resource.Address(), strconv.Quote(k))
+	case float64:
+		return fmt.Sprintf("%s[%d]", i.resource.Address(), int(k))
+	default:
+		return i.resource.Address()
+	}
+}
+
+// Attributes returns the attributes of the instance
+func (i StateInstance) Attributes() StateObject {
+	attrs, ok := i.m["attributes"].(map[string]interface{})
+	if !ok {
+		attrs = make(map[string]interface{})
+		i.m["attributes"] = attrs
+	}
+	return StateObject{path: i.Address(), m: attrs}
+}
+
+// StateObject is the attributes of an instance, or of a nested block of it
+type StateObject struct {
+	// path describes where the object is in the state for error messages
+	path string
+	m    map[string]interface{}
+}
+
+// Value returns the value of the attribute
+func (o StateObject) Value(key string) (interface{}, error) {
+	v, ok := o.m[key]
+	if !ok {
+		return nil, fmt.Errorf("tfstate: %s has no attribute %q", o.path, key)
+	}
+	return v, nil
+}
+
+// String returns the value of the string attribute. Null is returned as "".
+func (o StateObject) String(key string) (string, error) {
+	v, err := o.Value(key)
+	if err != nil {
+		return "", err
+	}
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	default:
+		return "", fmt.Errorf("tfstate: attribute %q of %s is %T, not a string", key, o.path, v)
+	}
+}
+
+// Set sets the value of the attribute
+func (o StateObject) Set(key string, v interface{}) {
+	o.m[key] = v
+}
+
+// NestedBlock returns the nested block of the type that has the name, e.g. backend "origin" of a service
+func (o StateObject) NestedBlock(blockType, name string) (StateObject, error) {
+	return o.NestedBlockBy(blockType, "name", name)
+}
+
+// NestedBlockBy returns the nested block of the type whose attribute has the value, e.g. the dictionary with the dictionary_id
+func (o StateObject) NestedBlockBy(blockType, key, value string) (StateObject, error) {
+	v, ok := o.m[blockType]
+	if !ok || v == nil {
+		return StateObject{}, fmt.Errorf("tfstate: %s has no %s blocks", o.path, blockType)
+	}
+	blocks, ok := v.([]interface{})
+	if !ok {
+		return StateObject{}, fmt.Errorf("tfstate: %s of %s is %T, not a list of blocks", blockType, o.path, v)
+	}
+	for _, b := range blocks {
+		m, ok := b.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if s, _ := m[key].(string); s == value {
+			path := fmt.Sprintf("%s %s %s", o.path, blockType, strconv.Quote(value))
+			if key != "name" {
+				path = fmt.Sprintf("%s %s with %s %s", o.path, blockType, key, strconv.Quote(value))
+			}
+			return StateObject{path: path, m: m}, nil
+		}
+	}
+	return StateObject{}, fmt.Errorf("tfstate: %s has no %s with %s %s", o.path, blockType, key, strconv.Quote(value))
+}
+
+// Resources returns the resources in the state
+func (s *TFState) Resources() ([]StateResource, error) {
+	v, ok := s.Value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("tfstate: unexpected state format: %T", s.Value)
+	}
+	list, _ := v["resources"].([]interface{})
+	resources := make([]StateResource, 0, len(list))
+	for _, r := range list {
+		m, ok := r.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("tfstate: unexpected resource format: %T", r)
+		}
+		resources = append(resources, StateResource{m: m})
+	}
+	return resources, nil
+}
+
+// Resource returns the managed resource of the type that has the name
+func (s *TFState) Resource(resourceType, name string) (StateResource, error) {
+	resources, err := s.Resources()
+	if err != nil {
+		return StateResource{}, err
+	}
+	for _, r := range resources {
+		if r.Mode() == "managed" && r.Type() == resourceType && r.Name() == name {
+			return r, nil
+		}
+	}
+	return StateResource{}, fmt.Errorf("tfstate: %s.%s is not found in the state", resourceType, name)
+}
+
+// Attributes returns the attributes of the only instance of the managed resource
+func (s *TFState) Attributes(resourceType, name string) (StateObject, error) {
+	r, err := s.Resource(resourceType, name)
+	if err != nil {
+		return StateObject{}, err
+	}
+	i, err := r.Instance()
+	if err != nil {
+		return StateObject{}, err
+	}
+	return i.Attributes(), nil
+}
+
+// Clone returns a deep copy of the state, so that the changes to it do not affect s
+func (s *TFState) Clone() (*TFState, error) {
+	b, err := json.Marshal(s.Value)
+	if err != nil {
+		return nil, err
+	}
+	var c TFState
+	if err := json.Unmarshal(b, &c.Value); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
@@ -0,0 +1,169 @@
+package terraformify
+
+import (
+	"strings"
+	"testing"
+)
+
+const surgeryState = `{"serial": 1, "resources": [
+	{"mode": "managed", "type": "fastly_service_vcl", "name": "service", "instances": [{"attributes": {
+		"id": "svc1",
+		"activate": false,
+		"backend": [{"name": "R&D's origin", "address": "example.com"}],
+		"dictionary": [{"dictionary_id": "dict1", "name": "geo"}]
+	}}]},
+	{"mode": "managed", "type": "fastly_service_dictionary_items", "name": "geo", "instances": [{"attributes": {"dictionary_id": "dict1", "manage_items": false}}]},
+	{"mode": "managed", "type": "fastly_service_acl_entries", "name": "acl", "instances": [{"attributes": {"acl_id": "acl1", "manage_entries": false}}]}
+]}`
+
+func TestSetIndexKey(t *testing.T) {
+	s := parseTestState(t, surgeryState)
+
+	// The key is stored as is, even with the characters that broke the query templates
+	key := `R&D's "geo"`
+	newState, err := s.SetIndexKey("fastly_service_dictionary_items", "geo", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := newState.Resource("fastly_service_dictionary_items", "geo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	i, err := r.Instance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i.IndexKey() != key {
+		t.Errorf("got %v, want %q", i.IndexKey(), key)
+	}
+	if want := `fastly_service_dictionary_items.geo["R&D's \"geo\""]`; i.Address() != want {
+		t.Errorf("got %s, want %s", i.Address(), want)
+	}
+
+	// The original state is not changed
+	r, _ = s.Resource("fastly_service_dictionary_items", "geo")
+	if i, _ := r.Instance(); i.IndexKey() != nil {
+		t.Errorf("the original state is changed: %v", i.IndexKey())
+	}
+
+	_, err = s.SetIndexKey("fastly_service_dictionary_items", "missing", "missing")
+	if err == nil || !strings.Contains(err.Error(), "fastly_service_dictionary_items.missing is not found") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestNestedBlock(t *testing.T) {
+	s := parseTestState(t, surgeryState)
+	attrs, err := s.Attributes("fastly_service_vcl", "service")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backend, err := attrs.NestedBlock("backend", "R&D's origin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := backend.String("address"); err != nil || v != "example.com" {
+		t.Errorf("got %q, %v, want %q", v, err, "example.com")
+	}
+
+	dictionary, err := attrs.NestedBlockBy("dictionary", "dictionary_id", "dict1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := dictionary.String("name"); v != "geo" {
+		t.Errorf("got %q, want %q", v, "geo")
+	}
+
+	testCases := []struct {
+		get  func() error
+		want string
+	}{
+		{
+			get: func() error {
+				_, err := attrs.NestedBlock("backend", "missing")
+				return err
+			},
+			want: `fastly_service_vcl.service has no backend with name "missing"`,
+		},
+		{
+			get: func() error {
+				_, err := attrs.NestedBlock("logging_s3", "s3")
+				return err
+			},
+			want: `fastly_service_vcl.service has no logging_s3 blocks`,
+		},
+		{
+			get: func() error {
+				_, err := backend.String("port")
+				return err
+			},
+			want: `fastly_service_vcl.service backend "R&D's origin" has no attribute "port"`,
+		},
+		{
+			get: func() error {
+				_, err := attrs.String("activate")
+				return err
+			},
+			want: `attribute "activate" of fastly_service_vcl.service is bool, not a string`,
+		},
+	}
+	for _, tt := range testCases {
+		err := tt.get()
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("got %v, want %q", err, tt.want)
+		}
+	}
+}
+
+func TestSetManageAttrs(t *testing.T) {
+	s := parseTestState(t, surgeryState)
+
+	newState, err := s.SetManageAttrs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		resourceType, name, attr string
+	}{
+		{"fastly_service_dictionary_items", "geo", "manage_items"},
+		{"fastly_service_acl_entries", "acl", "manage_entries"},
+	}
+	for _, tt := range testCases {
+		attrs, err := newState.Attributes(tt.resourceType, tt.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v, _ := attrs.Value(tt.attr); v != true {
+			t.Errorf("%s of %s.%s is %v, want true", tt.attr, tt.resourceType, tt.name, v)
+		}
+	}
+	attrs, _ := newState.Attributes("fastly_service_vcl", "service")
+	if v, _ := attrs.Value("activate"); v != false {
+		t.Errorf("activate is changed: %v", v)
+	}
+}
+
+func TestRenameResource(t *testing.T) {
+	s := parseTestState(t, surgeryState)
+
+	newState, err := s.RenameResource("fastly_service_acl_entries", "acl", "blocklist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newState.Resource("fastly_service_acl_entries", "blocklist"); err != nil {
+		t.Error(err)
+	}
+	if _, err := s.Resource("fastly_service_acl_entries", "acl"); err != nil {
+		t.Errorf("the original state is changed: %v", err)
+	}
+
+	// Query is left for the cases that the typed accessors do not cover
+	v, err := newState.Query(`.resources | map(.name) | join(",")`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.String() != "service,geo,blocklist" {
+		t.Errorf("got %s", v)
+	}
+}
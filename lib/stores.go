@@ -0,0 +1,84 @@
+package terraformify
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+)
+
+// ResourceLink is a resource_link block of the service, linking a store to the service
+type ResourceLink struct {
+	Name       string
+	ResourceID string
+}
+
+// GetResourceLinks returns the resource_link blocks of the service resource
+func (tfconf *TFConf) GetResourceLinks(serviceProp *VCLServiceResourceProp) ([]ResourceLink, error) {
+	for _, block := range tfconf.Body().Blocks() {
+		labels := block.Labels()
+		if block.Type() != "resource" || len(labels) != 2 || labels[0] != serviceProp.GetType() {
+			continue
+		}
+
+		links := make([]ResourceLink, 0)
+		for _, nested := range block.Body().Blocks() {
+			if nested.Type() != "resource_link" {
+				continue
+			}
+			name, err := getStringAttributeValue(nested, "name")
+			if err != nil {
+				return nil, err
+			}
+			id, err := getStringAttributeValue(nested, "resource_id")
+			if err != nil {
+				return nil, err
+			}
+			links = append(links, ResourceLink{Name: name, ResourceID: id})
+		}
+		return links, nil
+	}
+	return nil, fmt.Errorf("tfconf: %s is not found", serviceProp.GetRef())
+}
+
+// DiscoverLinkedStores looks up the KV stores, config stores and secret stores linked to the service.
+// The config stores come with their entries. The secrets in the secret stores are never imported.
+func (c *FastlyClient) DiscoverLinkedStores(links []ResourceLink, serviceProp *VCLServiceResourceProp) ([]TFBlockProp, error) {
+	props := make([]TFBlockProp, 0, len(links))
+	for _, link := range links {
+		id := url.PathEscape(link.ResourceID)
+		var store struct {
+			Name string `json:"name"`
+		}
+
+		err := c.get("/resources/stores/kv/"+id, nil, &store)
+		if err == nil {
+			props = append(props, NewKVStoreResourceProp(link.ResourceID, store.Name, serviceProp))
+			continue
+		} else if !errors.Is(err, ErrAPINotFound) {
+			return nil, err
+		}
+
+		err = c.get("/resources/stores/config/"+id, nil, &store)
+		if err == nil {
+			props = append(props,
+				NewConfigStoreResourceProp(link.ResourceID, store.Name, serviceProp),
+				NewConfigStoreEntriesResourceProp(link.ResourceID, link.Name, serviceProp))
+			continue
+		} else if !errors.Is(err, ErrAPINotFound) {
+			return nil, err
+		}
+
+		err = c.get("/resources/stores/secret/"+id, nil, &store)
+		if err == nil {
+			log.Printf("[WARN] The secrets in the secret store %s are not imported. Manage them outside of Terraform", store.Name)
+			props = append(props, NewSecretStoreResourceProp(link.ResourceID, store.Name, serviceProp))
+			continue
+		} else if !errors.Is(err, ErrAPINotFound) {
+			return nil, err
+		}
+
+		log.Printf("[WARN] The resource %s linked as %s is not a known store. Skipping", link.ResourceID, link.Name)
+	}
+	return props, nil
+}
@@ -0,0 +1,143 @@
+package terraformify
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDiscoverLinkedStores(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/resources/stores/kv/kv1":
+			w.Write([]byte(`{"id":"kv1","name":"my-kv"}`))
+		case "/resources/stores/config/cs1":
+			w.Write([]byte(`{"id":"cs1","name":"my-config"}`))
+		case "/resources/stores/secret/ss1":
+			w.Write([]byte(`{"id":"ss1","name":"my-secrets"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	client := NewFastlyClient("key")
+	client.Endpoint = ts.URL
+	serviceProp := NewVCLServiceResourceProp("svc", "service", 0)
+
+	props, err := client.DiscoverLinkedStores([]ResourceLink{
+		{Name: "kv", ResourceID: "kv1"},
+		{Name: "config", ResourceID: "cs1"},
+		{Name: "secrets", ResourceID: "ss1"},
+		{Name: "unknown", ResourceID: "xx1"},
+	}, serviceProp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"fastly_kvstore.my-kv",
+		"fastly_configstore.my-config",
+		"fastly_configstore_entries.config",
+		"fastly_secretstore.my-secrets",
+	}
+	if len(props) != len(want) {
+		t.Fatalf("got %d props, want %d", len(props), len(want))
+	}
+	for i, prop := range props {
+		if prop.GetRef() != want[i] {
+			t.Errorf("props[%d] = %s, want %s", i, prop.GetRef(), want[i])
+		}
+	}
+}
+
+const storesRawHCL = `# fastly_service_vcl.service:
+resource "fastly_service_vcl" "service" {
+    id   = "svc"
+    name = "example"
+
+    resource_link {
+        link_id     = "link1"
+        name        = "config"
+        resource_id = "cs1"
+    }
+}
+
+# fastly_configstore.my_config:
+resource "fastly_configstore" "my_config" {
+    id   = "cs1"
+    name = "my-config"
+}
+
+# fastly_configstore_entries.config:
+resource "fastly_configstore_entries" "config" {
+    entries  = {
+        "key" = "value"
+    }
+    id       = "cs1"
+    store_id = "cs1"
+}
+`
+
+const storesState = `{
+  "version": 4,
+  "resources": [
+    {
+      "mode": "managed",
+      "type": "fastly_service_vcl",
+      "name": "service",
+      "instances": [{"attributes": {"id": "svc", "resource_link": [{"link_id": "link1", "name": "config", "resource_id": "cs1"}]}}]
+    },
+    {
+      "mode": "managed",
+      "type": "fastly_configstore",
+      "name": "my_config",
+      "instances": [{"attributes": {"id": "cs1", "name": "my-config"}}]
+    },
+    {
+      "mode": "managed",
+      "type": "fastly_configstore_entries",
+      "name": "config",
+      "instances": [{"attributes": {"id": "cs1", "store_id": "cs1"}}]
+    }
+  ]
+}
+`
+
+const storesGolden = `# fastly_service_vcl.service:
+resource "fastly_service_vcl" "service" {
+  name = "example"
+
+  resource_link {
+    name        = "config"
+    resource_id = fastly_configstore.my_config.id
+  }
+  comment = ""
+}
+
+# fastly_configstore.my_config:
+resource "fastly_configstore" "my_config" {
+  name = "my-config"
+}
+
+# fastly_configstore_entries.config:
+resource "fastly_configstore_entries" "config" {
+  entries = {
+    "key" = "value"
+  }
+  store_id = each.value.resource_id
+  for_each = {
+    for d in fastly_service_vcl.service.resource_link : d.name => d if d.name == "config"
+  }
+  manage_entries = true
+}
+`
+
+func TestRewriteStoreResources(t *testing.T) {
+	tfstate := parseTestState(t, storesState)
+	result, _ := rewriteTestResources(t, storesRawHCL, tfstate, Config{ID: "svc", ManageAll: true})
+
+	if string(result) != storesGolden {
+		t.Errorf("unexpected result:\n%s", result)
+	}
+}
@@ -1,7 +1,9 @@
 package terraformify
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -10,11 +12,17 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"sort"
+	"sync"
 
 	"github.com/hashicorp/go-version"
 	"github.com/hashicorp/hc-install/product"
 	"github.com/hashicorp/hc-install/releases"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
 	"github.com/hashicorp/terraform-exec/tfexec"
+	"github.com/zclconf/go-cty/cty"
 )
 
 const tfVersion = "1.1.9"
@@ -51,22 +59,163 @@ func TerraformInstall(workingDir string) (*tfexec.Terraform, error) {
 
 func CreateInitTerraformFiles(c Config) (*os.File, error) {
 	// Create provider.tf
-	path := filepath.Join(c.Directory, "provider.tf")
-	if err := os.WriteFile(path, []byte(requiredProvider), 0644); err != nil {
+	if err := CreateProviderFile(c); err != nil {
 		return nil, err
 	}
 
 	// Create temp*.tf with empty service resource blocks
-	tempf, err := os.CreateTemp(c.Directory, "temp*.tf")
+	return CreateTempFile(c)
+}
+
+func CreateProviderFile(c Config) error {
+	path := filepath.Join(c.Directory, "provider.tf")
+	if c.Backend == "" {
+		return os.WriteFile(path, []byte(requiredProvider), 0644)
+	}
+	b, err := buildProviderWithBackend(c.Backend, c.BackendConfig)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0644)
+}
+
+// Settings of the backends that hold credentials. They are passed to "terraform init" as -backend-config,
+// which keeps them in the .terraform directory, instead of being written to provider.tf.
+var backendCredentialKeys = []string{
+	"access_key", "access_token", "client_certificate_password", "client_secret", "conn_str",
+	"credentials", "encryption_key", "password", "sas_token", "secret_key", "token",
+}
+
+func isBackendCredential(key string) bool {
+	for _, k := range backendCredentialKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// buildProviderWithBackend adds the backend block to the terraform block of requiredProvider.
+// The values are written as strings, and Terraform converts them to the types of the backend settings
+// as it does for "terraform init -backend-config". Credentials are left out.
+func buildProviderWithBackend(backendType string, config map[string]string) ([]byte, error) {
+	f, diags := hclwrite.ParseConfig([]byte(requiredProvider), "provider.tf", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("errors: %s", diags)
+	}
+	terraform := f.Body().FirstMatchingBlock("terraform", nil)
+
+	keys := make([]string, 0, len(config))
+	for k := range config {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	terraform.Body().AppendNewline()
+	backend := terraform.Body().AppendNewBlock("backend", []string{backendType}).Body()
+	for _, k := range keys {
+		if !hclsyntax.ValidIdentifier(k) {
+			return nil, fmt.Errorf("invalid backend config key %q", k)
+		}
+		if isBackendCredential(k) {
+			continue
+		}
+		backend.SetAttributeValue(k, cty.StringVal(config[k]))
+	}
+	return hclwrite.Format(f.Bytes()), nil
+}
+
+// backendCredentialOptions returns the credentials in the backend settings as -backend-config options of "terraform init"
+func backendCredentialOptions(config map[string]string) []tfexec.InitOption {
+	keys := make([]string, 0, len(config))
+	for k := range config {
+		if isBackendCredential(k) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	opts := make([]tfexec.InitOption, 0, len(keys))
+	for _, k := range keys {
+		opts = append(opts, tfexec.BackendConfig(k+"="+config[k]))
+	}
+	return opts
+}
+
+func CreateTempFile(c Config) (*os.File, error) {
+	return os.CreateTemp(c.Directory, "temp*.tf")
+}
+
+// TerraformInit runs "terraform init". The providers are upgraded only in the directories terraformify creates,
+// so that the lock file of an existing root module is kept. The credentials of the backend are given as -backend-config.
+func TerraformInit(tf *tfexec.Terraform, c Config) error {
+	opts := append([]tfexec.InitOption{tfexec.Upgrade(!c.Into)}, backendCredentialOptions(c.BackendConfig)...)
+	return tf.Init(context.Background(), opts...)
+}
+
+func TerraformWorkspaceSelect(tf *tfexec.Terraform, workspace string) error {
+	return tf.WorkspaceSelect(context.Background(), workspace)
+}
+
+// ExistingResourceAddresses returns the addresses of the resources in the configuration and the state of the working directory
+func ExistingResourceAddresses(tf *tfexec.Terraform) ([]string, error) {
+	addrs, err := ConfigResourceAddresses(tf.WorkingDir())
 	if err != nil {
 		return nil, err
 	}
 
-	return tempf, nil
+	stateAddrs, err := StateResourceAddresses(tf)
+	if err != nil {
+		return nil, err
+	}
+	return append(addrs, stateAddrs...), nil
+}
+
+// StateResourceAddresses returns the addresses of the resources in the state of the working directory
+func StateResourceAddresses(tf *tfexec.Terraform) ([]string, error) {
+	out, err := terraformExec(tf, "state", "pull")
+	if err != nil {
+		return nil, err
+	}
+	// The state is empty if nothing has been applied yet
+	if len(bytes.TrimSpace(out)) == 0 {
+		return nil, nil
+	}
+	var s TFState
+	if err := json.Unmarshal(out, &s.Value); err != nil {
+		return nil, fmt.Errorf("tfstate: invalid json: %w", err)
+	}
+	return s.ResourceAddresses(), nil
+}
+
+// RemoveNewResources removes the resources that are in the state but not in before,
+// so that a failed import leaves the state of the root module as it was
+func RemoveNewResources(tf *tfexec.Terraform, before []string) ([]string, error) {
+	addrs, err := StateResourceAddresses(tf)
+	if err != nil {
+		return nil, err
+	}
+	removed := NewAddresses(before, addrs)
+	if len(removed) == 0 {
+		return removed, nil
+	}
+	_, err = terraformExec(tf, append([]string{"state", "rm"}, removed...)...)
+	return removed, err
 }
 
-func TerraformInit(tf *tfexec.Terraform) error {
-	return tf.Init(context.Background(), tfexec.Upgrade(true))
+// NewAddresses returns the addresses in after that are not in before
+func NewAddresses(before, after []string) []string {
+	existed := make(map[string]bool, len(before))
+	for _, addr := range before {
+		existed[addr] = true
+	}
+	added := make([]string, 0)
+	for _, addr := range after {
+		if !existed[addr] {
+			added = append(added, addr)
+		}
+	}
+	return added
 }
 
 func TerraformVersion(tf *tfexec.Terraform) error {
@@ -84,8 +233,7 @@ func TerraformVersion(tf *tfexec.Terraform) error {
 
 func TerraformImport(tf *tfexec.Terraform, prop TFBlockProp, f io.Writer) error {
 	// Add the empty resource block to the file
-	_, err := fmt.Fprintf(f, "resource \"%s\" \"%s\" {}\n", prop.GetType(), prop.GetNormalizedName())
-	if err != nil {
+	if err := writeEmptyResourceBlock(prop, f); err != nil {
 		return err
 	}
 
@@ -97,10 +245,194 @@ func TerraformImport(tf *tfexec.Terraform, prop TFBlockProp, f io.Writer) error
 	return nil
 }
 
+// TerraformImportParallel runs "terraform import" for up to parallelism props at a time.
+// Each import writes to its own state file so that the runs do not contend for the state lock.
+// The results are then merged into the state in the backend. Only the local backend supports the per-resource state files.
+func TerraformImportParallel(tf *tfexec.Terraform, props []TFBlockProp, f io.Writer, parallelism int) error {
+	// All resource blocks have to be in place before any of the imports starts
+	for _, prop := range props {
+		if err := writeEmptyResourceBlock(prop, f); err != nil {
+			return err
+		}
+	}
+
+	tempDir, err := os.MkdirTemp("", "terraformify")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tempDir)
+
+	paths := make([]string, len(props))
+	errs := make([]error, len(props))
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+
+	for i, prop := range props {
+		paths[i] = filepath.Join(tempDir, fmt.Sprintf("%d.tfstate", i))
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, prop TFBlockProp) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			log.Printf(`[INFO] Running "terraform import" on %s`, prop.GetRef())
+			err := tf.Import(context.Background(), prop.GetRef(), prop.GetIDforTFImport(), tfexec.State(paths[i]), tfexec.StateOut(paths[i]))
+			if err != nil {
+				errs[i] = fmt.Errorf("%s: %w", prop.GetRef(), err)
+			}
+		}(i, prop)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	// Merge the per-resource state files into the state
+	log.Print("[INFO] Merging the imported resources into the state")
+	tfstate, err := PullTFState(tf)
+	if err != nil {
+		return err
+	}
+	states := make([]*TFState, 0, len(paths))
+	for _, path := range paths {
+		s, err := loadTFStateFile(path)
+		if err != nil {
+			return err
+		}
+		states = append(states, s)
+	}
+	tfstate, err = tfstate.MergeResources(states...)
+	if err != nil {
+		return err
+	}
+
+	return PushTFState(tf, tfstate)
+}
+
+// TerraformRename renames the resource that has been imported as from to the current name of the prop.
+// The state is updated in place, and the temp*.tf file, which must only have the resource block of the prop at this point,
+// is rewritten with the new name.
+func TerraformRename(tf *tfexec.Terraform, prop TFBlockProp, from string, f *os.File) error {
+	tfstate, err := PullTFState(tf)
+	if err != nil {
+		return err
+	}
+	tfstate, err = tfstate.RenameResource(prop.GetType(), from, prop.GetNormalizedName())
+	if err != nil {
+		return err
+	}
+	if err := PushTFState(tf, tfstate); err != nil {
+		return err
+	}
+
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return writeEmptyResourceBlock(prop, f)
+}
+
+func writeEmptyResourceBlock(prop TFBlockProp, f io.Writer) error {
+	_, err := fmt.Fprintf(f, "resource \"%s\" \"%s\" {}\n", prop.GetType(), prop.GetNormalizedName())
+	return err
+}
+
+// TerraformShow returns the current state in HCL format.
+// The state is pulled from the backend so that it works with remote backends as well.
 func TerraformShow(tf *tfexec.Terraform) (string, error) {
-	return tf.ShowPlanFileRaw(context.Background(), "terraform.tfstate")
+	out, err := terraformExec(tf, "state", "pull")
+	if err != nil {
+		return "", err
+	}
+
+	tempDir, err := os.MkdirTemp("", "terraformify")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tempDir)
+
+	path := filepath.Join(tempDir, "terraform.tfstate")
+	if err := os.WriteFile(path, out, 0644); err != nil {
+		return "", err
+	}
+	return tf.ShowPlanFileRaw(context.Background(), path)
+}
+
+// PullTFState reads the current state from the backend with "terraform state pull"
+func PullTFState(tf *tfexec.Terraform) (*TFState, error) {
+	out, err := terraformExec(tf, "state", "pull")
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(out)) == 0 {
+		return nil, fmt.Errorf("tfstate: no state in the backend of %s", tf.WorkingDir())
+	}
+
+	var s TFState
+	if err := json.Unmarshal(out, &s.Value); err != nil {
+		return nil, fmt.Errorf("tfstate: invalid json: %w", err)
+	}
+	return &s, nil
+}
+
+// PushTFState writes the state to the backend with "terraform state push".
+// The state must have been pulled from the backend and modified. The serial is incremented
+// so that the backend accepts it as the newer state, and the lineage is kept so that it is accepted as the same state.
+func PushTFState(tf *tfexec.Terraform, s *TFState) error {
+	v, ok := s.Value.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("tfstate: unexpected state format: %T", s.Value)
+	}
+	if _, ok := v["lineage"].(string); !ok {
+		return fmt.Errorf("tfstate: the state has no lineage")
+	}
+	serial, ok := v["serial"].(float64)
+	if !ok {
+		return fmt.Errorf("tfstate: the state has no serial")
+	}
+
+	pushed := make(map[string]interface{}, len(v))
+	for k, val := range v {
+		pushed[k] = val
+	}
+	pushed["serial"] = serial + 1
+
+	tempDir, err := os.MkdirTemp("", "terraformify")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tempDir)
+
+	path := filepath.Join(tempDir, "terraform.tfstate")
+	if err := os.WriteFile(path, TFState{Value: pushed}.Bytes(), 0644); err != nil {
+		return err
+	}
+	_, err = terraformExec(tf, "state", "push", path)
+	return err
 }
 
 func TerraformRefresh(tf *tfexec.Terraform) error {
 	return tf.Refresh(context.Background())
 }
+
+// terraformExec runs the Terraform command that tfexec does not support, such as "plan -refresh-only",
+// in the working directory and returns the stdout
+func terraformExec(tf *tfexec.Terraform, args ...string) ([]byte, error) {
+	cmd := exec.Command(tf.ExecPath(), args...)
+	cmd.Dir = tf.WorkingDir()
+	cmd.Env = append(os.Environ(), "TF_IN_AUTOMATION=1")
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("terraform %s: %w\n%s", args[0], err, stderr.String())
+	}
+	return out, nil
+}
@@ -0,0 +1,64 @@
+package terraformify
+
+import (
+	"testing"
+)
+
+func TestBuildProviderWithBackend(t *testing.T) {
+	config, err := ParseBackendConfig([]string{
+		"bucket=tfstate",
+		"key=fastly/terraform.tfstate",
+		"endpoint=http://localhost:9000",
+		"force_path_style=true",
+		"max_retries=1",
+		"secret_key=SECRET",
+		"workspace_key_prefix=0123",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := buildProviderWithBackend("s3", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `terraform {
+  required_providers {
+    fastly = {
+      source  = "fastly/fastly"
+      version = ">= 2.0.0"
+    }
+  }
+
+  backend "s3" {
+    bucket               = "tfstate"
+    endpoint             = "http://localhost:9000"
+    force_path_style     = "true"
+    key                  = "fastly/terraform.tfstate"
+    max_retries          = "1"
+    workspace_key_prefix = "0123"
+  }
+}`
+	if string(b) != expected {
+		t.Errorf("unexpected provider.tf:\n%s", b)
+	}
+
+	// The credentials are given to terraform init instead
+	if opts := backendCredentialOptions(config); len(opts) != 1 {
+		t.Errorf("got %d -backend-config options, want 1", len(opts))
+	}
+
+	if _, err := ParseBackendConfig([]string{"bucket"}); err == nil {
+		t.Error("expected an error for a setting without a value")
+	}
+}
+
+func TestNewAddresses(t *testing.T) {
+	before := []string{"fastly_service_vcl.www", "fastly_service_dictionary_items.geo"}
+	after := []string{"fastly_service_vcl.www", "fastly_service_dictionary_items.geo", "fastly_service_vcl.api", "fastly_service_acl_entries.allow"}
+	got := NewAddresses(before, after)
+	want := []string{"fastly_service_vcl.api", "fastly_service_acl_entries.allow"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -1,12 +1,18 @@
 package terraformify
 
 import (
+	"bytes"
+	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclsyntax"
@@ -108,12 +114,46 @@ func (tfconf *TFConf) ParseVCLServiceResource(serviceProp *VCLServiceResourcePro
 	return props, nil
 }
 
-func (tfconf *TFConf) RewriteResources(serviceProp *VCLServiceResourceProp, c Config) ([]byte, error) {
-	// Read terraform.tfstate into the variable
-	tfstate, err := LoadTFState(c.Directory)
-	if err != nil {
-		return nil, err
+// GetServiceName returns the name attribute of the service resource block
+func (tfconf *TFConf) GetServiceName(serviceProp *VCLServiceResourceProp) (string, error) {
+	for _, block := range tfconf.Body().Blocks() {
+		labels := block.Labels()
+		if block.Type() != "resource" || len(labels) != 2 || labels[0] != serviceProp.GetType() {
+			continue
+		}
+		if labels[1] != serviceProp.GetNormalizedName() {
+			continue
+		}
+		return getStringAttributeValue(block, "name")
+	}
+	return "", fmt.Errorf("tfconf: %s is not found", serviceProp.GetRef())
+}
+
+// GetNestedBlockID returns the ID attribute of the named nested block of the service resource block, e.g. dictionary_id of a dictionary
+func (tfconf *TFConf) GetNestedBlockID(serviceProp *VCLServiceResourceProp, blockType, idName, name string) (string, error) {
+	for _, block := range tfconf.Body().Blocks() {
+		labels := block.Labels()
+		if block.Type() != "resource" || len(labels) != 2 || labels[0] != serviceProp.GetType() || labels[1] != serviceProp.GetNormalizedName() {
+			continue
+		}
+		for _, nested := range block.Body().Blocks() {
+			if nested.Type() != blockType {
+				continue
+			}
+			n, err := getStringAttributeValue(nested, "name")
+			if err != nil || n != name {
+				continue
+			}
+			return getStringAttributeValue(nested, idName)
+		}
+		return "", fmt.Errorf("tfconf: %s %q is not found in %s", blockType, name, serviceProp.GetRef())
 	}
+	return "", fmt.Errorf("tfconf: %s is not found", serviceProp.GetRef())
+}
+
+// RewriteResources rewrites the resource blocks of the configuration, reading the values that "terraform show" omits from the state
+func (tfconf *TFConf) RewriteResources(serviceProp *VCLServiceResourceProp, tfstate *TFState, c Config) ([]byte, error) {
+	c.written = make(map[string]string)
 
 	// Read resource blocks
 	for _, block := range tfconf.Body().Blocks() {
@@ -146,13 +186,26 @@ func (tfconf *TFConf) RewriteResources(serviceProp *VCLServiceResourceProp, c Co
 			if err != nil {
 				return nil, err
 			}
+		case "fastly_kvstore", "fastly_configstore", "fastly_secretstore":
+			rewriteStoreResource(block)
+		case "fastly_configstore_entries":
+			err := rewriteCommonAttributes(block, serviceProp, tfstate, c)
+			if err != nil {
+				return nil, err
+			}
 		}
 	}
-	return tfconf.Bytes(), nil
+	if c.MergeForEach {
+		if err := tfconf.mergeForEachResources(serviceProp, c); err != nil {
+			return nil, err
+		}
+	}
+	// The expressions built from raw tokens, such as the local maps and templatefile, are indented
+	return hclwrite.Format(tfconf.Bytes()), nil
 }
 
 func rewriteVCLServiceResource(block *hclwrite.Block, serviceProp *VCLServiceResourceProp, s *TFState, c Config) error {
-	tfstate, err := s.addQueryTemplate(serviceQueryTmpl)
+	attrs, err := s.Attributes(serviceProp.GetType(), serviceProp.GetNormalizedName())
 	if err != nil {
 		return err
 	}
@@ -177,11 +230,26 @@ func rewriteVCLServiceResource(block *hclwrite.Block, serviceProp *VCLServiceRes
 		}
 	}
 
+	// Map the store IDs to the references to the store resources
+	storeRefs, err := s.idReferences("fastly_kvstore", "fastly_configstore", "fastly_secretstore")
+	if err != nil {
+		return err
+	}
+
 	for _, block := range body.Blocks() {
 		blockType := block.Type()
 		nestedBlock := block.Body()
 
 		switch blockType {
+		case "resource_link":
+			nestedBlock.RemoveAttribute("link_id")
+			id, err := getStringAttributeValue(block, "resource_id")
+			if err != nil {
+				return err
+			}
+			if ref, ok := storeRefs[id]; ok {
+				nestedBlock.SetAttributeTraversal("resource_id", ref)
+			}
 		case "acl":
 			nestedBlock.RemoveAttribute("acl_id")
 		case "dictionary":
@@ -197,18 +265,17 @@ func rewriteVCLServiceResource(block *hclwrite.Block, serviceProp *VCLServiceRes
 			}
 			keys := []string{"ssl_client_cert", "ssl_client_key"}
 
+			backend, err := attrs.NestedBlock(blockType, name)
+			if err != nil {
+				return err
+			}
 			for _, key := range keys {
-				v, err := tfstate.Query(QueryParams{
-					ResourceName:  serviceProp.GetNormalizedName(),
-					AttributeType: blockType,
-					Name:          name,
-					Query:         key,
-				})
+				v, err := backend.String(key)
 				if err != nil {
 					return err
 				}
-				if v.String() != "" {
-					nestedBlock.SetAttributeValue(key, cty.StringVal(v.String()))
+				if v != "" {
+					nestedBlock.SetAttributeValue(key, cty.StringVal(v))
 				}
 			}
 		case "request_setting":
@@ -219,12 +286,11 @@ func rewriteVCLServiceResource(block *hclwrite.Block, serviceProp *VCLServiceRes
 			}
 
 			// Get content from TFState
-			v, err := tfstate.Query(QueryParams{
-				ResourceName:  serviceProp.GetNormalizedName(),
-				AttributeType: blockType,
-				Name:          name,
-				Query:         "xff",
-			})
+			setting, err := attrs.NestedBlock(blockType, name)
+			if err != nil {
+				return err
+			}
+			xff, err := setting.String("xff")
 			if err != nil {
 				return err
 			}
@@ -232,7 +298,7 @@ func rewriteVCLServiceResource(block *hclwrite.Block, serviceProp *VCLServiceRes
 			// In the provider schema, xff is an optional attribute with a default value of "append"
 			// Because of the default value, Terraform attempts to add the default value even if the value is not set for the actual service.
 			// To workaround the issue, explicitly setting xff attribute with blank value if it's blank in the state file
-			if v.String() == "" {
+			if xff == "" {
 				nestedBlock.SetAttributeValue("xff", cty.StringVal(""))
 			}
 		case "response_object":
@@ -242,25 +308,29 @@ func rewriteVCLServiceResource(block *hclwrite.Block, serviceProp *VCLServiceRes
 				return err
 			}
 
-			// Get content from TFState
-			v, err := tfstate.Query(QueryParams{
-				ResourceName:  serviceProp.GetNormalizedName(),
-				AttributeType: blockType,
-				Name:          name,
-				Query:         "content",
-			})
+			// Get content and its type from TFState
+			nested, err := attrs.NestedBlock(blockType, name)
+			if err != nil {
+				return err
+			}
+			content, err := nested.String("content")
 			if err != nil {
 				return err
 			}
+			contentType, _ := nested.String("content_type")
 
-			ext := "txt"
-			filename := fmt.Sprintf("%s.%s", normalize(name), ext)
-			if err = saveContent(c.Directory, filename, v.Bytes()); err != nil {
+			// The state has the content as a string, in which the API has replaced the invalid UTF-8 sequences of binary bodies
+			if strings.ContainsRune(content, utf8.RuneError) {
+				log.Printf("[WARN] The content of response object %q is not valid UTF-8 and may differ from the original bytes", name)
+			}
+
+			vars := FileVars{Type: blockType, Name: normalize(name), Ext: contentExtension(contentType, content)}
+			path, err := c.writeFile("content", vars, []byte(content))
+			if err != nil {
 				return err
 			}
 
 			// Replace content attribute of the nested block with file function expression
-			path := fmt.Sprintf("./content/%s", filename)
 			tokens := buildFileFunction(path)
 			nestedBlock.SetAttributeRaw("content", tokens)
 		case "snippet":
@@ -271,24 +341,22 @@ func rewriteVCLServiceResource(block *hclwrite.Block, serviceProp *VCLServiceRes
 			}
 
 			// Get content from TFState
-			v, err := tfstate.Query(QueryParams{
-				ResourceName:  serviceProp.GetNormalizedName(),
-				AttributeType: blockType,
-				Name:          name,
-				Query:         "content",
-			})
+			snippet, err := attrs.NestedBlock(blockType, name)
+			if err != nil {
+				return err
+			}
+			content, err := snippet.String("content")
 			if err != nil {
 				return err
 			}
 
 			// Save content to a file
-			filename := fmt.Sprintf("snippet_%s.vcl", normalize(name))
-			if err = saveVCL(c.Directory, filename, v.Bytes()); err != nil {
+			path, err := c.writeFile("snippet", snippetFileVars(blockType, snippet), []byte(content))
+			if err != nil {
 				return err
 			}
 
 			// Replace content attribute of the nested block with file function expression
-			path := fmt.Sprintf("./vcl/%s", filename)
 			tokens := buildFileFunction(path)
 			nestedBlock.SetAttributeRaw("content", tokens)
 		case "vcl":
@@ -299,24 +367,19 @@ func rewriteVCLServiceResource(block *hclwrite.Block, serviceProp *VCLServiceRes
 			}
 
 			// Get content from TFState
-			v, err := tfstate.Query(QueryParams{
-				ResourceName:  serviceProp.GetNormalizedName(),
-				AttributeType: blockType,
-				Name:          name,
-				Query:         "content",
-			})
+			content, err := nestedBlockString(attrs, blockType, name, "content")
 			if err != nil {
 				return err
 			}
 
 			// Save content to a file
-			filename := fmt.Sprintf("%s.vcl", normalize(name))
-			if err = saveVCL(c.Directory, filename, v.Bytes()); err != nil {
+			vars := FileVars{Type: blockType, Name: normalize(name), Ext: "vcl"}
+			path, err := c.writeFile("vcl", vars, []byte(content))
+			if err != nil {
 				return err
 			}
 
 			// Replace content attribute of the nested block with file function expression
-			path := fmt.Sprintf("./vcl/%s", filename)
 			tokens := buildFileFunction(path)
 			nestedBlock.SetAttributeRaw("content", tokens)
 		default:
@@ -325,23 +388,40 @@ func rewriteVCLServiceResource(block *hclwrite.Block, serviceProp *VCLServiceRes
 				if err != nil {
 					return err
 				}
-				format, err := tfstate.Query(QueryParams{
-					ResourceName:  serviceProp.GetNormalizedName(),
-					AttributeType: blockType,
-					Name:          name,
-					Query:         "format",
-				})
+				logging, err := attrs.NestedBlock(blockType, name)
+				if err != nil {
+					return err
+				}
+				format, err := logging.String("format")
+				if err != nil {
+					return err
+				}
 				ext := "txt"
-				if json.Valid(format.Bytes()) {
+				if json.Valid([]byte(format)) {
 					ext = "json"
 				}
-				filename := fmt.Sprintf("%s.%s", normalize(name), ext)
-				if err = saveLogFormat(c.Directory, filename, format.Bytes()); err != nil {
+				vars := FileVars{Type: blockType, Name: normalize(name), Ext: ext}
+
+				// Write the format as a template if it has the values specific to the environment
+				var tmplVars map[string]string
+				var names []string
+				if c.LogFormatTemplates {
+					tmplVars = logFormatVars(attrs, logging)
+					var tmpl string
+					if tmpl, names = buildLogFormatTemplate(format, tmplVars); len(names) > 0 {
+						format = tmpl
+						vars.Ext += ".tftpl"
+					}
+				}
+				path, err := c.writeFile("logformat", vars, []byte(format))
+				if err != nil {
 					return err
 				}
 				// Replace content attribute of the nested block with file function expression
-				path := fmt.Sprintf("./logformat/%s", filename)
 				tokens := buildFileFunction(path)
+				if len(names) > 0 {
+					tokens = buildTemplateFileFunction(path, tmplVars, names)
+				}
 				nestedBlock.SetAttributeRaw("format", tokens)
 
 				// Populate sensitive attributes from the state file
@@ -395,16 +475,11 @@ func rewriteVCLServiceResource(block *hclwrite.Block, serviceProp *VCLServiceRes
 					keys = []string{"tls_client_key"}
 				}
 				for _, key := range keys {
-					v, err := tfstate.Query(QueryParams{
-						ResourceName:  serviceProp.GetNormalizedName(),
-						AttributeType: blockType,
-						Name:          name,
-						Query:         key,
-					})
+					v, err := logging.String(key)
 					if err != nil {
 						return err
 					}
-					nestedBlock.SetAttributeValue(key, cty.StringVal(v.String()))
+					nestedBlock.SetAttributeValue(key, cty.StringVal(v))
 				}
 			}
 		}
@@ -412,6 +487,15 @@ func rewriteVCLServiceResource(block *hclwrite.Block, serviceProp *VCLServiceRes
 	return nil
 }
 
+// nestedBlockString returns the string attribute of the named nested block of the service in the state
+func nestedBlockString(attrs StateObject, blockType, name, key string) (string, error) {
+	nested, err := attrs.NestedBlock(blockType, name)
+	if err != nil {
+		return "", err
+	}
+	return nested.String(key)
+}
+
 func rewriteACLResource(block *hclwrite.Block, serviceProp *VCLServiceResourceProp, s *TFState, c Config) error {
 	err := rewriteCommonAttributes(block, serviceProp, s, c)
 	if err != nil {
@@ -420,7 +504,8 @@ func rewriteACLResource(block *hclwrite.Block, serviceProp *VCLServiceResourcePr
 
 	// remove read-only attributes from each ACL entry
 	body := block.Body()
-	for _, block := range body.Blocks() {
+	entries := body.Blocks()
+	for _, block := range entries {
 		t := block.Type()
 		nb := block.Body()
 		if t != "entry" {
@@ -429,11 +514,124 @@ func rewriteACLResource(block *hclwrite.Block, serviceProp *VCLServiceResourcePr
 		nb.RemoveAttribute("id")
 	}
 
+	if c.ExtractThreshold == 0 || len(entries) <= c.ExtractThreshold {
+		return nil
+	}
+
+	// Write the entries to a CSV file and replace the entry blocks with a dynamic block
+	name := block.Labels()[1]
+	attrs, err := s.Attributes(block.Labels()[0], name)
+	if err != nil {
+		return err
+	}
+	v, err := attrs.Value("entry")
+	if err != nil {
+		return err
+	}
+	csv, err := buildACLEntriesCSV(v)
+	if err != nil {
+		return err
+	}
+
+	filename := fmt.Sprintf("%s.csv", normalize(name))
+	if err = saveACLEntries(c.fileDir(), filename, csv); err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		body.RemoveBlock(entry)
+	}
+	body.AppendNewline()
+	dynamic := body.AppendNewBlock("dynamic", []string{"entry"})
+	path := c.filePath("acls", filename)
+	dynamic.Body().SetAttributeRaw("for_each", buildDecodeFileFunction("csvdecode", path))
+	content := dynamic.Body().AppendNewBlock("content", nil).Body()
+	for _, key := range aclEntryKeys {
+		content.SetAttributeTraversal(key, hcl.Traversal{
+			hcl.TraverseRoot{Name: "entry"},
+			hcl.TraverseAttr{Name: "value"},
+			hcl.TraverseAttr{Name: key},
+		})
+	}
+
 	return nil
 }
 
 func rewriteDictionaryResource(block *hclwrite.Block, serviceProp *VCLServiceResourceProp, s *TFState, c Config) error {
-	return rewriteCommonAttributes(block, serviceProp, s, c)
+	err := rewriteCommonAttributes(block, serviceProp, s, c)
+	if err != nil {
+		return err
+	}
+
+	if c.ExtractThreshold == 0 {
+		return nil
+	}
+
+	// Get the items from the state file
+	name := block.Labels()[1]
+	attrs, err := s.Attributes(block.Labels()[0], name)
+	if err != nil {
+		return err
+	}
+	v, err := attrs.Value("items")
+	if err != nil {
+		return err
+	}
+	items, _ := v.(map[string]interface{})
+	if len(items) <= c.ExtractThreshold {
+		return nil
+	}
+
+	// Write the items to a JSON file and replace the items attribute with jsondecode(file())
+	b, err := json.MarshalIndent(items, "", "  ")
+	if err != nil {
+		return err
+	}
+	filename := fmt.Sprintf("%s.json", normalize(name))
+	if err = saveDictionaryItems(c.fileDir(), filename, append(b, '\n')); err != nil {
+		return err
+	}
+
+	path := c.filePath("dictionaries", filename)
+	block.Body().SetAttributeRaw("items", buildDecodeFileFunction("jsondecode", path))
+
+	return nil
+}
+
+// Attributes of ACL entries written to the CSV file
+var aclEntryKeys = []string{"ip", "subnet", "negated", "comment"}
+
+func buildACLEntriesCSV(v interface{}) ([]byte, error) {
+	entries, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected ACL entries: %#v", v)
+	}
+
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+	if err := w.Write(aclEntryKeys); err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		entry, ok := e.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("unexpected ACL entry: %#v", e)
+		}
+		record := make([]string, 0, len(aclEntryKeys))
+		for _, key := range aclEntryKeys {
+			if entry[key] == nil {
+				record = append(record, "")
+				continue
+			}
+			record = append(record, fmt.Sprint(entry[key]))
+		}
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+
+	return b.Bytes(), w.Error()
 }
 
 func rewriteDynamicSnippetResource(block *hclwrite.Block, serviceProp *VCLServiceResourceProp, s *TFState, c Config) error {
@@ -446,26 +644,39 @@ func rewriteDynamicSnippetResource(block *hclwrite.Block, serviceProp *VCLServic
 	name := block.Labels()[1]
 
 	// Get content from the state file
-	tfstate, err := s.addQueryTemplate(dsnippetQueryTmpl)
+	attrs, err := s.Attributes(block.Labels()[0], name)
 	if err != nil {
 		return err
 	}
-	v, err := tfstate.Query(QueryParams{
-		ResourceName: name,
-	})
+	content, err := attrs.String("content")
 	if err != nil {
 		return err
 	}
 
+	// The type and priority of the snippet are in the service
+	snippetID, err := attrs.String("snippet_id")
+	if err != nil {
+		return err
+	}
+	serviceAttrs, err := s.Attributes(serviceProp.GetType(), serviceProp.GetNormalizedName())
+	if err != nil {
+		return err
+	}
+	snippet, err := serviceAttrs.NestedBlockBy("dynamicsnippet", "snippet_id", snippetID)
+	if err != nil {
+		return err
+	}
+	vars := snippetFileVars("dynamicsnippet", snippet)
+	vars.Name = normalize(name)
+
 	// Save content to a file
-	filename := fmt.Sprintf("dsnippet_%s.vcl", normalize(name))
-	if err = saveVCL(c.Directory, filename, v.Bytes()); err != nil {
+	path, err := c.writeFile("dynamicsnippet", vars, []byte(content))
+	if err != nil {
 		return err
 	}
 
 	// Replace content attribute with file function expression
 	body := block.Body()
-	path := fmt.Sprintf("./vcl/%s", filename)
 	tokens := buildFileFunction(path)
 	body.SetAttributeRaw("content", tokens)
 
@@ -474,6 +685,9 @@ func rewriteDynamicSnippetResource(block *hclwrite.Block, serviceProp *VCLServic
 
 func rewriteCommonAttributes(block *hclwrite.Block, serviceProp *VCLServiceResourceProp, s *TFState, c Config) error {
 	var idName, attrType string
+	// The ID attribute in the nested block of the service, if it differs from idName
+	var refName string
+	manageAttr := "manage_items"
 	switch block.Labels()[0] {
 	case "fastly_service_dynamic_snippet_content":
 		idName = "snippet_id"
@@ -484,6 +698,14 @@ func rewriteCommonAttributes(block *hclwrite.Block, serviceProp *VCLServiceResou
 	case "fastly_service_acl_entries":
 		idName = "acl_id"
 		attrType = "acl"
+	case "fastly_configstore_entries":
+		idName = "store_id"
+		refName = "resource_id"
+		attrType = "resource_link"
+		manageAttr = "manage_entries"
+	}
+	if refName == "" {
+		refName = idName
 	}
 
 	// Getting the name of the resource from the state file
@@ -491,15 +713,15 @@ func rewriteCommonAttributes(block *hclwrite.Block, serviceProp *VCLServiceResou
 	if err != nil {
 		return err
 	}
-	tfstate, err := s.addResourceQueryTemplate(resourceNameQueryTmpl)
+	attrs, err := s.Attributes(serviceProp.GetType(), serviceProp.GetNormalizedName())
 	if err != nil {
 		return err
 	}
-	name, err := tfstate.Query(ResourceNameQueryParams{
-		AttributeType: attrType,
-		IDName:        idName,
-		ID:            id,
-	})
+	nested, err := attrs.NestedBlockBy(attrType, refName, id)
+	if err != nil {
+		return err
+	}
+	name, err := nested.String("name")
 	if err != nil {
 		return err
 	}
@@ -507,28 +729,36 @@ func rewriteCommonAttributes(block *hclwrite.Block, serviceProp *VCLServiceResou
 	body := block.Body()
 
 	// Add for_each to the resource block
-	tokens := buildForEach(attrType, name.String())
+	tokens := buildForEach(serviceProp, attrType, name)
 	body.SetAttributeRaw("for_each", tokens)
 
-	// Setting the resource ID (acl_id, dictionary_id, snippet_id)
-	resourceIDRef := buildForEachIDRef(idName)
+	// Setting the resource ID (acl_id, dictionary_id, snippet_id, store_id)
+	resourceIDRef := buildForEachIDRef(refName)
 	body.SetAttributeTraversal(idName, resourceIDRef)
 
 	// remove read-only attributes
 	body.RemoveAttribute("id")
 
 	// set service_id to represent the resource dependency
-	ref := buildServiceIDRef(serviceProp)
-	body.SetAttributeTraversal("service_id", ref)
+	// Config stores are not bound to a service, and depend on it through for_each
+	if attrType != "resource_link" {
+		ref := buildServiceIDRef(serviceProp)
+		body.SetAttributeTraversal("service_id", ref)
+	}
 
 	if c.ManageAll {
-		// set manage_items to true
-		body.SetAttributeValue("manage_items", cty.BoolVal(true))
+		// set manage_items (manage_entries for config stores) to true
+		body.SetAttributeValue(manageAttr, cty.BoolVal(true))
 	}
 
 	return nil
 }
 
+func rewriteStoreResource(block *hclwrite.Block) {
+	// remove read-only attributes
+	block.Body().RemoveAttribute("id")
+}
+
 func rewriteWAFResource(block *hclwrite.Block, serviceProp *VCLServiceResourceProp) error {
 	body := block.Body()
 	// remove read-only attributes
@@ -550,26 +780,49 @@ func rewriteWAFResource(block *hclwrite.Block, serviceProp *VCLServiceResourcePr
 }
 
 func buildFileFunction(path string) hclwrite.Tokens {
-	return hclwrite.Tokens{
+	tokens := hclwrite.Tokens{
 		{Type: hclsyntax.TokenIdent, Bytes: []byte("file")},
 		{Type: hclsyntax.TokenOParen, Bytes: []byte{'('}},
-		{Type: hclsyntax.TokenOQuote, Bytes: []byte{'"'}},
-		{Type: hclsyntax.TokenQuotedLit, Bytes: []byte(path)},
-		{Type: hclsyntax.TokenCQuote, Bytes: []byte{'"'}},
-		{Type: hclsyntax.TokenCParen, Bytes: []byte{')'}},
 	}
+	tokens = append(tokens, buildModulePath(path)...)
+	return append(tokens, &hclwrite.Token{Type: hclsyntax.TokenCParen, Bytes: []byte{')'}})
 }
 
-func buildForEach(resourceType, name string) hclwrite.Tokens {
+// buildModulePath builds the string of the path relative to the module, e.g. "${path.module}/vcl/main.vcl",
+// so that the configuration also works when it is used as a module
+func buildModulePath(path string) hclwrite.Tokens {
+	// Escaped quoted string of the rest of the path
+	lit := hclwrite.TokensForValue(cty.StringVal("/" + path))
+	tokens := hclwrite.Tokens{
+		lit[0],
+		{Type: hclsyntax.TokenTemplateInterp, Bytes: []byte("${")},
+		{Type: hclsyntax.TokenIdent, Bytes: []byte("path")},
+		{Type: hclsyntax.TokenDot, Bytes: []byte{'.'}},
+		{Type: hclsyntax.TokenIdent, Bytes: []byte("module")},
+		{Type: hclsyntax.TokenTemplateSeqEnd, Bytes: []byte{'}'}},
+	}
+	return append(tokens, lit[1:]...)
+}
+
+func buildDecodeFileFunction(decoder, path string) hclwrite.Tokens {
+	tokens := hclwrite.Tokens{
+		{Type: hclsyntax.TokenIdent, Bytes: []byte(decoder)},
+		{Type: hclsyntax.TokenOParen, Bytes: []byte{'('}},
+	}
+	tokens = append(tokens, buildFileFunction(path)...)
+	return append(tokens, &hclwrite.Token{Type: hclsyntax.TokenCParen, Bytes: []byte{')'}})
+}
+
+func buildForEach(serviceProp *VCLServiceResourceProp, resourceType, name string) hclwrite.Tokens {
 	return hclwrite.Tokens{
 		{Type: hclsyntax.TokenOBrace, Bytes: []byte{'{'}, SpacesBefore: 1},
 		{Type: hclsyntax.TokenNewline, Bytes: []byte("\n"), SpacesBefore: 0},
 		{Type: hclsyntax.TokenIdent, Bytes: []byte("for"), SpacesBefore: 2},
 		{Type: hclsyntax.TokenIdent, Bytes: []byte("d"), SpacesBefore: 1},
 		{Type: hclsyntax.TokenIdent, Bytes: []byte("in"), SpacesBefore: 1},
-		{Type: hclsyntax.TokenIdent, Bytes: []byte("fastly_service_vcl"), SpacesBefore: 1},
+		{Type: hclsyntax.TokenIdent, Bytes: []byte(serviceProp.GetType()), SpacesBefore: 1},
 		{Type: hclsyntax.TokenDot, Bytes: []byte{'.'}, SpacesBefore: 0},
-		{Type: hclsyntax.TokenIdent, Bytes: []byte("service"), SpacesBefore: 0},
+		{Type: hclsyntax.TokenIdent, Bytes: []byte(serviceProp.GetNormalizedName()), SpacesBefore: 0},
 		{Type: hclsyntax.TokenDot, Bytes: []byte{'.'}, SpacesBefore: 0},
 		{Type: hclsyntax.TokenIdent, Bytes: []byte(resourceType), SpacesBefore: 0},
 		{Type: hclsyntax.TokenColon, Bytes: []byte{':'}, SpacesBefore: 1},
@@ -607,6 +860,141 @@ func buildServiceIDRef(serviceProp *VCLServiceResourceProp) hcl.Traversal {
 	}
 }
 
+// getAttributeValue evaluates the expression of the attribute.
+// It only succeeds for expressions that do not refer to anything such as literals.
+func getAttributeValue(block *hclwrite.Block, attrKey string) (cty.Value, error) {
+	attr := block.Body().GetAttribute(attrKey)
+	if attr == nil {
+		return cty.NilVal, fmt.Errorf(`%w: failed to find "%s" in "%s"`, ErrAttrNotFound, attrKey, block.Type())
+	}
+
+	// A trailing newline is required to close heredocs
+	src := append(attr.Expr().BuildTokens(nil).Bytes(), '\n')
+	expr, diags := hclsyntax.ParseExpression(src, "", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return cty.NilVal, fmt.Errorf("failed to parse %s: %s", attrKey, diags)
+	}
+	v, diags := expr.Value(nil)
+	if diags.HasErrors() {
+		return cty.NilVal, fmt.Errorf("failed to evaluate %s: %s", attrKey, diags)
+	}
+	return v, nil
+}
+
+// buildExpression returns the tokens of the HCL expression
+func buildExpression(src string) (hclwrite.Tokens, error) {
+	f, diags := hclwrite.ParseConfig([]byte("expr = "+src+"\n"), "", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("invalid expression %s: %s", src, diags)
+	}
+	return f.Body().GetAttribute("expr").Expr().BuildTokens(nil), nil
+}
+
+// SelectResources returns the configuration that only has the resource blocks for which keep returns true
+func (tfconf *TFConf) SelectResources(keep func(resourceType, name string) bool) *TFConf {
+	f := hclwrite.NewEmptyFile()
+	body := f.Body()
+	for _, block := range tfconf.Body().Blocks() {
+		labels := block.Labels()
+		if block.Type() != "resource" || len(labels) != 2 || !keep(labels[0], labels[1]) {
+			continue
+		}
+		if len(body.Blocks()) > 0 {
+			body.AppendNewline()
+		}
+		body.AppendBlock(block)
+	}
+	return &TFConf{f}
+}
+
+// SelectProps returns the configuration that only has the resource blocks of the props
+func (tfconf *TFConf) SelectProps(props ...TFBlockProp) *TFConf {
+	keys := make(map[string]bool, len(props))
+	for _, prop := range props {
+		keys[prop.GetRef()] = true
+	}
+	return tfconf.SelectResources(func(resourceType, name string) bool {
+		return keys[resourceType+"."+name]
+	})
+}
+
+// loadConfigFiles parses the configuration files of the directory in the order of their names
+func loadConfigFiles(dir string) ([]*hclwrite.File, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	parsed := make([]*hclwrite.File, 0, len(files))
+	for _, file := range files {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		f, diags := hclwrite.ParseConfig(b, file, hcl.Pos{Line: 1, Column: 1})
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("errors: %s", diags)
+		}
+		parsed = append(parsed, f)
+	}
+	return parsed, nil
+}
+
+// configBodies returns the bodies of the files
+func configBodies(files []*hclwrite.File) []*hclwrite.Body {
+	bodies := make([]*hclwrite.Body, 0, len(files))
+	for _, f := range files {
+		bodies = append(bodies, f.Body())
+	}
+	return bodies
+}
+
+// ConfigResourceAddresses returns the addresses of the resources in the configuration files of the directory
+func ConfigResourceAddresses(dir string) ([]string, error) {
+	files, err := loadConfigFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	addrs := make([]string, 0)
+	for _, f := range files {
+		for _, block := range f.Body().Blocks() {
+			labels := block.Labels()
+			if block.Type() == "resource" && len(labels) == 2 {
+				addrs = append(addrs, labels[0]+"."+labels[1])
+			}
+		}
+	}
+	return addrs, nil
+}
+
+// ConfiguredBackend returns the type of the backend configured in the terraform blocks of the directory,
+// "cloud" for Terraform Cloud, or "" if none is configured and the local backend is used
+func ConfiguredBackend(dir string) (string, error) {
+	files, err := loadConfigFiles(dir)
+	if err != nil {
+		return "", err
+	}
+	for _, f := range files {
+		for _, block := range f.Body().Blocks() {
+			if block.Type() != "terraform" {
+				continue
+			}
+			for _, nested := range block.Body().Blocks() {
+				switch nested.Type() {
+				case "backend":
+					if labels := nested.Labels(); len(labels) == 1 {
+						return labels[0], nil
+					}
+				case "cloud":
+					return "cloud", nil
+				}
+			}
+		}
+	}
+	return "", nil
+}
+
 func getStringAttributeValue(block *hclwrite.Block, attrKey string) (string, error) {
 	// find TokenQuotedLit
 	attr := block.Body().GetAttribute(attrKey)
@@ -630,27 +1018,49 @@ func getStringAttributeValue(block *hclwrite.Block, attrKey string) (string, err
 	return value, nil
 }
 
-func saveContent(workingDir, name string, content []byte) error {
-	return saveFile(workingDir, name, "content", content)
+// writeFile writes the content extracted from the service to the path of the layout of the kind,
+// and returns the path to refer to it in the configuration.
+// With Dedupe, the content is written to the common directory named after its hash, so that the same content is written once.
+func (c Config) writeFile(kind string, vars FileVars, content []byte) (string, error) {
+	if c.Dedupe {
+		// e.g. common/vcl/<hash>.vcl for both VCL and snippets
+		p := path.Join(commonDir, path.Dir(DefaultFileLayouts[kind]), contentHash(content)+"."+vars.Ext)
+		if err := saveFile(c.Directory, p, content); err != nil {
+			return "", err
+		}
+		return p, nil
+	}
+	p, err := c.layoutPath(kind, vars)
+	if err != nil {
+		return "", err
+	}
+	modulePath := path.Join(c.FileDir, p)
+	if c.written != nil {
+		hash := contentHash(content)
+		if h, ok := c.written[modulePath]; ok && h != hash {
+			return "", fmt.Errorf("tfconf: %s %q is written to %s, which has the different content of another file. Change the layout with --layout so that the paths are unique", kind, vars.Name, modulePath)
+		}
+		c.written[modulePath] = hash
+	}
+	if err := saveFile(c.fileDir(), p, content); err != nil {
+		return "", err
+	}
+	return modulePath, nil
 }
 
-func saveVCL(workingDir, name string, content []byte) error {
-	return saveFile(workingDir, name, "vcl", content)
+func saveDictionaryItems(workingDir, name string, content []byte) error {
+	return saveFile(workingDir, path.Join("dictionaries", name), content)
 }
 
-func saveLogFormat(workingDir, name string, content []byte) error {
-	return saveFile(workingDir, name, "logformat", content)
+func saveACLEntries(workingDir, name string, content []byte) error {
+	return saveFile(workingDir, path.Join("acls", name), content)
 }
 
-func saveFile(workingDir, name, fileType string, content []byte) error {
-	dir := filepath.Join(workingDir, fileType)
-	if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
-		err := os.Mkdir(dir, 0755)
-		if err != nil {
-			return err
-		}
+// saveFile writes the content to the path, relative to workingDir, creating the directories of the path
+func saveFile(workingDir, name string, content []byte) error {
+	file := filepath.Join(workingDir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
 	}
-
-	file := filepath.Join(workingDir, fileType, name)
 	return os.WriteFile(file, content, 0644)
 }
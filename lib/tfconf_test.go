@@ -3,6 +3,7 @@ package terraformify
 import (
 	"bytes"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -11,60 +12,186 @@ const (
 	goldenFile = "../testdata/golden.tf"
 )
 
+// loadTestFixture returns the "terraform show" output and the state of the service in testdata
+func loadTestFixture(t *testing.T) (string, *TFState) {
+	t.Helper()
+	b, err := os.ReadFile(inputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tfstate, err := LoadTFState("../testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b), tfstate
+}
+
+// rewriteTestResources rewrites the resources of the service in rawHCL, writing the extracted files to a temporary directory.
+// It returns the result and the directory, which is set to c.Directory.
+func rewriteTestResources(t *testing.T, rawHCL string, tfstate *TFState, c Config) ([]byte, string) {
+	t.Helper()
+	c.Directory = t.TempDir()
+	tfconf, err := LoadTFConf(rawHCL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serviceProp := NewVCLServiceResourceProp(c.ID, "service", c.Version)
+	result, err := tfconf.RewriteResources(serviceProp, tfstate, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result, c.Directory
+}
+
 func TestRewriteResources(t *testing.T) {
 	testCases := []struct {
-		serviceID  string
-		version    int
-		workingDir string
+		serviceID string
+		version   int
 
 		manageAll bool
 	}{
 		{
-			serviceID:  "6gjZ23Y0k6TApEs5PxzYuT",
-			version:    0,
-			workingDir: "../testdata",
-			manageAll:  false,
+			serviceID: "6gjZ23Y0k6TApEs5PxzYuT",
+			version:   0,
+			manageAll: false,
 		},
 	}
 
+	rawHCL, tfstate := loadTestFixture(t)
+	expected, err := os.ReadFile(goldenFile)
+	if err != nil {
+		t.Fatal(err)
+	}
 	for _, tt := range testCases {
-		serviceProp := NewVCLServiceResourceProp(tt.serviceID, "service", tt.version)
 		config := Config{
 			ID:          tt.serviceID,
 			Version:     tt.version,
-			Directory:   tt.workingDir,
 			Interactive: false,
 			ManageAll:   tt.manageAll,
 		}
+		result, _ := rewriteTestResources(t, rawHCL, tfstate, config)
 
-		expected, err := os.ReadFile(goldenFile)
-		if err != nil {
-			t.Fatal(err)
+		if !bytes.Equal(expected, result) {
+			t.Logf("golden:\n%s\n", expected)
+			t.Logf("result:\n%s\n", result)
+			t.Error("Result content does not match golden file")
 		}
+	}
+}
 
-		b, err := os.ReadFile(inputFile)
-		if err != nil {
-			t.Fatal(err)
-		}
+func TestRewriteResourcesExtractThreshold(t *testing.T) {
+	config := Config{
+		ID:               "6gjZ23Y0k6TApEs5PxzYuT",
+		ExtractThreshold: 2,
+	}
+	rawHCL, tfstate := loadTestFixture(t)
+	result, dir := rewriteTestResources(t, rawHCL, tfstate, config)
 
-		tfconf, err := LoadTFConf(string(b))
-		if err != nil {
+	// redirect_table has 3 items and config_table has 2 items
+	if !bytes.Contains(result, []byte(`items         = jsondecode(file("${path.module}/dictionaries/redirect_table.json"))`)) {
+		t.Errorf("redirect_table is not extracted:\n%s", result)
+	}
+	if bytes.Contains(result, []byte(`dictionaries/config_table.json`)) {
+		t.Errorf("config_table should not be extracted:\n%s", result)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "acls")); err == nil {
+		t.Error("ACLs with 2 entries should not be extracted")
+	}
+
+	expected := `{
+  "/bar": "/image",
+  "/baz": "/image",
+  "/foo": "/image"
+}
+`
+	got, err := os.ReadFile(filepath.Join(dir, "dictionaries", "redirect_table.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != expected {
+		t.Errorf("unexpected dictionary file:\n%s", got)
+	}
+
+	// Extract the ACL entries as well
+	config.ExtractThreshold = 1
+	result, dir = rewriteTestResources(t, rawHCL, tfstate, config)
+
+	expectedBlock := `  dynamic "entry" {
+    for_each = csvdecode(file("${path.module}/acls/allow_list.csv"))
+    content {
+      ip      = entry.value.ip
+      subnet  = entry.value.subnet
+      negated = entry.value.negated
+      comment = entry.value.comment
+    }
+  }
+`
+	if !bytes.Contains(result, []byte(expectedBlock)) {
+		t.Errorf("allow_list is not extracted:\n%s", result)
+	}
+
+	expected = "ip,subnet,negated,comment\n192.168.0.0,24,false,ACL Entry 1\n192.168.1.0,24,false,ACL Entry 2\n"
+	got, err = os.ReadFile(filepath.Join(dir, "acls", "allow_list.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != expected {
+		t.Errorf("unexpected ACL file:\n%s", got)
+	}
+}
+
+func TestGetNestedBlockID(t *testing.T) {
+	tfconf, err := LoadTFConf(`resource "fastly_service_vcl" "example" {
+  name = "example"
+
+  dictionary {
+    dictionary_id = "dict1"
+    name          = "geo"
+  }
+  acl {
+    acl_id = "acl1"
+    name   = "blocklist"
+  }
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serviceProp := NewVCLServiceResourceProp("svc1", "example", 0)
+	serviceProp.SetNormalizedName("example")
+
+	id, err := tfconf.GetNestedBlockID(serviceProp, "acl", "acl_id", "blocklist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "acl1" {
+		t.Errorf("got %q, want %q", id, "acl1")
+	}
+	if _, err := tfconf.GetNestedBlockID(serviceProp, "dictionary", "dictionary_id", "blocklist"); err == nil {
+		t.Error("expected an error for a missing dictionary")
+	}
+}
+
+func TestConfiguredBackend(t *testing.T) {
+	testCases := []struct {
+		config   string
+		expected string
+	}{
+		{config: `provider "fastly" {}`, expected: ""},
+		{config: "terraform {\n  backend \"s3\" {\n    bucket = \"tfstate\"\n  }\n}\n", expected: "s3"},
+		{config: "terraform {\n  cloud {\n    organization = \"example\"\n  }\n}\n", expected: "cloud"},
+	}
+	for _, tt := range testCases {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "provider.tf"), []byte(tt.config), 0644); err != nil {
 			t.Fatal(err)
 		}
-
-		result, err := tfconf.RewriteResources(serviceProp, config)
+		got, err := ConfiguredBackend(dir)
 		if err != nil {
 			t.Fatal(err)
 		}
-
-		if !bytes.Equal(expected, result) {
-			t.Logf("golden:\n%s\n", expected)
-			t.Logf("result:\n%s\n", result)
-			t.Error("Result content does not match golden file")
+		if got != tt.expected {
+			t.Errorf("got %q, want %q for:\n%s", got, tt.expected, tt.config)
 		}
-
-		os.RemoveAll("../testdata/vcl")
-		os.RemoveAll("../testdata/content")
-		os.RemoveAll("../testdata/logformat")
 	}
 }
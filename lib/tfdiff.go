@@ -0,0 +1,249 @@
+package terraformify
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+type ChangeKind string
+
+const (
+	ChangeAdded    ChangeKind = "+"
+	ChangeRemoved  ChangeKind = "-"
+	ChangeModified ChangeKind = "~"
+)
+
+// BlockChange is a change of a resource block or a nested block of it.
+// From and To are the blocks before and after the change, nil if the block is added or removed.
+// Attributes holds the changed attributes of modified blocks.
+type BlockChange struct {
+	Kind       ChangeKind
+	Address    string
+	From       *hclwrite.Block
+	To         *hclwrite.Block
+	Attributes []AttributeChange
+}
+
+// AttributeChange is a change of an attribute, with the expressions before and after the change.
+// From or To is empty if the attribute is added or removed.
+type AttributeChange struct {
+	Name string
+	From string
+	To   string
+}
+
+func (c BlockChange) String() string {
+	return fmt.Sprintf("%s %s", c.Kind, c.Address)
+}
+
+// FileChange is a change of a file referred to by the configuration, such as VCL and log formats
+type FileChange struct {
+	Kind ChangeKind
+	Path string
+}
+
+func (c FileChange) String() string {
+	return fmt.Sprintf("%s %s", c.Kind, c.Path)
+}
+
+// DiffBlocks compares the resource blocks of two configurations.
+// Resources are matched by address, and nested blocks by block type and name, so that
+// reordered blocks are not reported as changes.
+func DiffBlocks(from, to *TFConf) []BlockChange {
+	fromBlocks := resourceBlocks(from)
+	toBlocks := resourceBlocks(to)
+
+	changes := make([]BlockChange, 0)
+	for _, addr := range unionKeys(fromBlocks, toBlocks) {
+		changes = append(changes, diffBlock(addr, fromBlocks[addr], toBlocks[addr])...)
+	}
+	return changes
+}
+
+func diffBlock(addr string, from, to *hclwrite.Block) []BlockChange {
+	switch {
+	case from == nil:
+		return []BlockChange{{Kind: ChangeAdded, Address: addr, To: to}}
+	case to == nil:
+		return []BlockChange{{Kind: ChangeRemoved, Address: addr, From: from}}
+	}
+
+	changes := make([]BlockChange, 0)
+	if attrs := diffAttributes(from.Body(), to.Body()); len(attrs) > 0 {
+		changes = append(changes, BlockChange{Kind: ChangeModified, Address: addr, From: from, To: to, Attributes: attrs})
+	}
+
+	fromNested := nestedBlocks(from.Body())
+	toNested := nestedBlocks(to.Body())
+	for _, key := range unionKeys(fromNested, toNested) {
+		changes = append(changes, diffBlock(addr+"."+key, fromNested[key], toNested[key])...)
+	}
+	return changes
+}
+
+func diffAttributes(from, to *hclwrite.Body) []AttributeChange {
+	fromAttrs := attributeExprs(from)
+	toAttrs := attributeExprs(to)
+
+	names := make([]string, 0, len(fromAttrs)+len(toAttrs))
+	for name := range fromAttrs {
+		names = append(names, name)
+	}
+	for name := range toAttrs {
+		if _, ok := fromAttrs[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	changes := make([]AttributeChange, 0)
+	for _, name := range names {
+		if fromAttrs[name] != toAttrs[name] {
+			changes = append(changes, AttributeChange{Name: name, From: fromAttrs[name], To: toAttrs[name]})
+		}
+	}
+	return changes
+}
+
+// attributeExprs maps the attribute names of the body to the expressions
+func attributeExprs(body *hclwrite.Body) map[string]string {
+	exprs := make(map[string]string)
+	for name, attr := range body.Attributes() {
+		// Format the expression so that the alignment of the attributes does not matter
+		exprs[name] = string(bytes.TrimSpace(hclwrite.Format(attr.Expr().BuildTokens(nil).Bytes())))
+	}
+	return exprs
+}
+
+// resourceBlocks maps the resource addresses to the resource blocks
+func resourceBlocks(tfconf *TFConf) map[string]*hclwrite.Block {
+	blocks := make(map[string]*hclwrite.Block)
+	for _, block := range tfconf.Body().Blocks() {
+		labels := block.Labels()
+		if block.Type() != "resource" || len(labels) != 2 {
+			continue
+		}
+		blocks[labels[0]+"."+labels[1]] = block
+	}
+	return blocks
+}
+
+// nestedBlocks maps the keys of the nested blocks, e.g. backend["origin"], to the blocks.
+// Blocks without a name are keyed by the position among the blocks of the same type.
+func nestedBlocks(body *hclwrite.Body) map[string]*hclwrite.Block {
+	blocks := make(map[string]*hclwrite.Block)
+	counts := make(map[string]int)
+	for _, block := range body.Blocks() {
+		var key string
+		name, err := getStringAttributeValue(block, "name")
+		if err == nil {
+			key = fmt.Sprintf("%s[%s]", block.Type(), strconv.Quote(name))
+		} else {
+			key = fmt.Sprintf("%s[%d]", block.Type(), counts[block.Type()])
+		}
+		counts[block.Type()]++
+
+		// Names are unique in most block types, but keep duplicates distinguishable
+		for i := 2; blocks[key] != nil; i++ {
+			key = fmt.Sprintf("%s[%s]#%d", block.Type(), strconv.Quote(name), i)
+		}
+		blocks[key] = block
+	}
+	return blocks
+}
+
+func unionKeys(a, b map[string]*hclwrite.Block) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// DiffFiles compares the files other than the *.tf files in the two working directories,
+// such as VCL, log formats and extracted dictionaries.
+func DiffFiles(fromDir, toDir string) ([]FileChange, error) {
+	fromFiles, err := listDataFiles(fromDir)
+	if err != nil {
+		return nil, err
+	}
+	toFiles, err := listDataFiles(toDir)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(fromFiles)+len(toFiles))
+	for path := range fromFiles {
+		paths = append(paths, path)
+	}
+	for path := range toFiles {
+		if !fromFiles[path] {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	changes := make([]FileChange, 0)
+	for _, path := range paths {
+		switch {
+		case !fromFiles[path]:
+			changes = append(changes, FileChange{Kind: ChangeAdded, Path: path})
+		case !toFiles[path]:
+			changes = append(changes, FileChange{Kind: ChangeRemoved, Path: path})
+		default:
+			a, err := os.ReadFile(filepath.Join(fromDir, path))
+			if err != nil {
+				return nil, err
+			}
+			b, err := os.ReadFile(filepath.Join(toDir, path))
+			if err != nil {
+				return nil, err
+			}
+			if !bytes.Equal(a, b) {
+				changes = append(changes, FileChange{Kind: ChangeModified, Path: path})
+			}
+		}
+	}
+	return changes, nil
+}
+
+// listDataFiles returns the relative paths of the files in the working directory,
+// skipping the *.tf files and the files managed by Terraform
+func listDataFiles(dir string) (map[string]bool, error) {
+	files := make(map[string]bool)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".terraform" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if filepath.Dir(rel) == "." {
+			// Top-level files are the configuration and the state
+			return nil
+		}
+		files[filepath.ToSlash(rel)] = true
+		return nil
+	})
+	return files, err
+}
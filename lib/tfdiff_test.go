@@ -0,0 +1,80 @@
+package terraformify
+
+import (
+	"reflect"
+	"testing"
+)
+
+const diffFromHCL = `resource "fastly_service_vcl" "service" {
+  name = "example"
+
+  backend {
+    address = "origin1.example.com"
+    name    = "origin1"
+  }
+  backend {
+    address = "origin2.example.com"
+    name    = "origin2"
+  }
+  domain {
+    name = "www.example.com"
+  }
+}
+
+resource "fastly_service_dictionary_items" "dict" {
+  items = {
+    "key" = "value"
+  }
+}
+`
+
+const diffToHCL = `resource "fastly_service_vcl" "service" {
+  name = "renamed"
+
+  domain {
+    name = "www.example.com"
+  }
+  backend {
+    name    = "origin2"
+    address = "origin2.example.com"
+  }
+  backend {
+    address = "origin3.example.com"
+    name    = "origin1"
+  }
+  domain {
+    name = "api.example.com"
+  }
+}
+`
+
+func TestDiffBlocks(t *testing.T) {
+	from, err := LoadTFConf(diffFromHCL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, err := LoadTFConf(diffToHCL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes := DiffBlocks(from, to)
+	got := make([]string, 0)
+	for _, c := range changes {
+		got = append(got, c.String())
+	}
+	want := []string{
+		`- fastly_service_dictionary_items.dict`,
+		`~ fastly_service_vcl.service`,
+		`~ fastly_service_vcl.service.backend["origin1"]`,
+		`+ fastly_service_vcl.service.domain["api.example.com"]`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	wantAttrs := []AttributeChange{{Name: "address", From: `"origin1.example.com"`, To: `"origin3.example.com"`}}
+	if !reflect.DeepEqual(changes[2].Attributes, wantAttrs) {
+		t.Errorf("got %q, want %q", changes[2].Attributes, wantAttrs)
+	}
+}
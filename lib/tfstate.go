@@ -1,72 +1,42 @@
 package terraformify
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
-	"html/template"
-	"log"
 	"os"
 	"path/filepath"
+	"sort"
 
 	"github.com/itchyny/gojq"
 )
 
-// query for gojq
-const setActivateQuery = `(.resources[] | select(.type == "fastly_service_vcl" or .type == "fastly_service_waf_configuration") | .instances[].attributes.activate) |= true`
-const setManageSnippetsQuery = `(.resources[] | select(.type == "fastly_service_dynamic_snippet_content") | .instances[].attributes.manage_snippets) |=true`
-const setManageItemsQuery = `(.resources[] | select(.type == "fastly_service_dictionary_items") | .instances[].attributes.manage_items) |=true`
-const setManageEntriesQuery = `(.resources[] | select(.type == "fastly_service_acl_entries") | .instances[].attributes.manage_entries) |=true`
-
-// query templates for gojq
-const serviceQueryTmpl = `.resources[] | select(.name == "{{.ResourceName}}") | .instances[].attributes.{{.AttributeType}}[] | select(.name == "{{.Name}}") | .{{.Query}}`
-const dsnippetQueryTmpl = `.resources[] | select(.name == "{{.ResourceName}}") | .instances[].attributes.content`
-const resourceNameQueryTmpl = `.resources[] | select(.type == "fastly_service_vcl") | .instances[].attributes.{{.AttributeType}}[] | select(.{{.IDName}} == "{{.ID}}") | .name`
-const SetIndexKeyQueryTmpl = `(.resources[] | select(.type == "{{.ResourceType}}") | select(.name == "{{.ResourceName}}") | .instances[]) += {index_key: "{{.Name}}"}`
-
-type QueryParams struct {
-	ResourceName  string
-	AttributeType string
-	Name          string
-	Query         string
-}
-
-type ResourceNameQueryParams struct {
-	AttributeType string
-	IDName        string
-	ID            string
+// Types of the resources whose activate attribute is set
+var activateResourceTypes = map[string]bool{
+	"fastly_service_vcl":               true,
+	"fastly_service_waf_configuration": true,
 }
 
-type IndexKeyQueryParams struct {
-	ResourceType string
-	ResourceName string
-	Name         string
+// manage_* attributes of the resource types
+var manageAttrs = map[string]string{
+	"fastly_service_dynamic_snippet_content": "manage_snippets",
+	"fastly_service_dictionary_items":        "manage_items",
+	"fastly_service_acl_entries":             "manage_entries",
+	"fastly_configstore_entries":             "manage_entries",
 }
 
 type TFState struct {
 	Value interface{}
 }
 
-type TFStateWithQueryTemplate struct {
-	*template.Template
-	*TFState
-}
-
-type TFStateWithResourceQueryTemplate struct {
-	*template.Template
-	*TFState
-}
-
-type TFStateWithIndexKeyQueryTemplate struct {
-	*template.Template
-	*TFState
-}
-
 func LoadTFState(workingDir string) (*TFState, error) {
 	file := filepath.Join(workingDir, "terraform.tfstate")
+	return loadTFStateFile(file)
+}
+
+func loadTFStateFile(file string) (*TFState, error) {
 	f, err := os.Open(file)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	defer f.Close()
 
@@ -78,31 +48,10 @@ func LoadTFState(workingDir string) (*TFState, error) {
 	return &s, nil
 }
 
-func (s *TFState) addQueryTemplate(tmpl string) (*TFStateWithQueryTemplate, error) {
-	t, err := template.New("template").Parse(tmpl)
-	if err != nil {
-		return nil, fmt.Errorf("tfstate: invalid template: %w", err)
-	}
-
-	return &TFStateWithQueryTemplate{t, s}, nil
-}
-
-func (s *TFState) addResourceQueryTemplate(tmpl string) (*TFStateWithResourceQueryTemplate, error) {
-	t, err := template.New("template").Parse(tmpl)
-	if err != nil {
-		return nil, fmt.Errorf("tfstate: invalid template: %w", err)
-	}
-
-	return &TFStateWithResourceQueryTemplate{t, s}, nil
-}
-
-func (s *TFState) AddIndexKeyQueryTemplate(tmpl string) (*TFStateWithIndexKeyQueryTemplate, error) {
-	t, err := template.New("template").Parse(tmpl)
-	if err != nil {
-		return nil, fmt.Errorf("tfstate: invalid template: %w", err)
-	}
-
-	return &TFStateWithIndexKeyQueryTemplate{t, s}, nil
+// Save writes the state to terraform.tfstate in the working directory
+func (s *TFState) Save(workingDir string) error {
+	path := filepath.Join(workingDir, "terraform.tfstate")
+	return os.WriteFile(path, s.Bytes(), 0644)
 }
 
 func (s TFState) Bytes() []byte {
@@ -119,6 +68,8 @@ func (s TFState) String() string {
 	return string(s.Bytes())
 }
 
+// Query runs the jq query against the state and returns the first result.
+// It covers what the typed accessors, such as Resource and NestedBlock, do not.
 func (s *TFState) Query(query string) (*TFState, error) {
 	jq, err := gojq.Parse(query)
 	if err != nil {
@@ -138,61 +89,229 @@ func (s *TFState) Query(query string) (*TFState, error) {
 	return nil, fmt.Errorf("tfstate: %s is not found in the state", query)
 }
 
-func (s *TFStateWithQueryTemplate) Query(params QueryParams) (*TFState, error) {
-	var query bytes.Buffer
-	if err := s.Execute(&query, params); err != nil {
-		return nil, fmt.Errorf("tfstate: invalid params: %w", err)
+// MergeResources returns a new state that has the resources of s and others.
+// The resources are sorted by mode, type and name so that the result does not depend on the order in which they were imported.
+func (s *TFState) MergeResources(others ...*TFState) (*TFState, error) {
+	dst, ok := s.Value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("tfstate: unexpected state format: %T", s.Value)
 	}
+	resources, _ := dst["resources"].([]interface{})
 
-	return s.TFState.Query(query.String())
-}
+	merged := make([]interface{}, 0, len(resources))
+	merged = append(merged, resources...)
+	for _, other := range others {
+		src, ok := other.Value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("tfstate: unexpected state format: %T", other.Value)
+		}
+		rs, _ := src["resources"].([]interface{})
+		merged = append(merged, rs...)
+	}
 
-func (s *TFStateWithResourceQueryTemplate) Query(params ResourceNameQueryParams) (*TFState, error) {
-	var query bytes.Buffer
-	if err := s.Execute(&query, params); err != nil {
-		return nil, fmt.Errorf("tfstate: invalid params: %w", err)
+	key := func(r interface{}) string {
+		m, _ := r.(map[string]interface{})
+		return fmt.Sprintf("%v\x00%v\x00%v", m["mode"], m["type"], m["name"])
 	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return key(merged[i]) < key(merged[j])
+	})
 
-	return s.TFState.Query(query.String())
+	v := make(map[string]interface{}, len(dst))
+	for k, val := range dst {
+		v[k] = val
+	}
+	v["resources"] = merged
+	if serial, ok := dst["serial"].(float64); ok {
+		v["serial"] = serial + 1
+	}
+
+	return &TFState{Value: v}, nil
 }
 
-func (s *TFStateWithIndexKeyQueryTemplate) Query(params IndexKeyQueryParams) (*TFState, error) {
-	var query bytes.Buffer
-	if err := s.Execute(&query, params); err != nil {
-		return nil, fmt.Errorf("tfstate: invalid params: %w", err)
+// SelectResources returns the state that only has the resources whose addresses are in addrs
+func (s *TFState) SelectResources(addrs []string) (*TFState, error) {
+	newState, err := s.Clone()
+	if err != nil {
+		return nil, err
+	}
+	keep := make(map[string]bool, len(addrs))
+	for _, addr := range addrs {
+		keep[addr] = true
+	}
+	resources, err := newState.Resources()
+	if err != nil {
+		return nil, err
 	}
+	selected := make([]interface{}, 0, len(resources))
+	for _, r := range resources {
+		if r.Mode() == "managed" && keep[r.Address()] {
+			selected = append(selected, r.m)
+		}
+	}
+	newState.Value.(map[string]interface{})["resources"] = selected
+	return newState, nil
+}
 
-	return s.TFState.Query(query.String())
+// ResourceNameByID returns the name of the resource of the type that has the ID
+func (s *TFState) ResourceNameByID(resourceType, id string) (string, bool) {
+	resources, err := s.Resources()
+	if err != nil {
+		return "", false
+	}
+	for _, r := range resources {
+		if r.Mode() != "managed" || r.Type() != resourceType {
+			continue
+		}
+		for _, i := range r.Instances() {
+			if v, _ := i.Attributes().String("id"); v == id {
+				return r.Name(), true
+			}
+		}
+	}
+	return "", false
 }
 
-func (s *TFState) SetActivateAttr() (*TFState, error) {
-	q := setActivateQuery
-	return s.Query(q)
+// RenameResource changes the name of the resource in the state
+func (s *TFState) RenameResource(resourceType, from, to string) (*TFState, error) {
+	newState, err := s.Clone()
+	if err != nil {
+		return nil, err
+	}
+	r, err := newState.Resource(resourceType, from)
+	if err != nil {
+		return nil, err
+	}
+	r.setName(to)
+	return newState, nil
+}
+
+// SetIndexKey sets the index key of the instance of the resource, which is imported without one,
+// to match the for_each expression in the configuration
+func (s *TFState) SetIndexKey(resourceType, name, key string) (*TFState, error) {
+	newState, err := s.Clone()
+	if err != nil {
+		return nil, err
+	}
+	r, err := newState.Resource(resourceType, name)
+	if err != nil {
+		return nil, err
+	}
+	instances := r.Instances()
+	if len(instances) == 0 {
+		return nil, fmt.Errorf("tfstate: %s has no instances", r.Address())
+	}
+	for _, i := range instances {
+		i.SetIndexKey(key)
+	}
+	return newState, nil
 }
 
-func (s *TFState) SetManageSnippetsAttr() (*TFState, error) {
-	q := setManageSnippetsQuery
-	return s.Query(q)
+// MergeInstances moves the instances of the resources of the type into one resource named to.
+// keys maps the names of the resources to the index keys of their instances in the merged resource.
+func (s *TFState) MergeInstances(resourceType, to string, keys map[string]string) (*TFState, error) {
+	newState, err := s.Clone()
+	if err != nil {
+		return nil, err
+	}
+	v, ok := newState.Value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("tfstate: unexpected state format: %T", newState.Value)
+	}
+	resources, err := newState.Resources()
+	if err != nil {
+		return nil, err
+	}
+
+	var merged map[string]interface{}
+	var instances []interface{}
+	kept := make([]interface{}, 0, len(resources))
+	found := make(map[string]bool, len(keys))
+	for _, r := range resources {
+		key, ok := keys[r.Name()]
+		if r.Mode() != "managed" || r.Type() != resourceType || !ok {
+			kept = append(kept, r.m)
+			continue
+		}
+		found[r.Name()] = true
+		for _, i := range r.Instances() {
+			i.SetIndexKey(key)
+			instances = append(instances, i.m)
+		}
+		if merged == nil {
+			// The merged resource takes the place of the first one
+			merged = r.m
+			kept = append(kept, merged)
+		}
+	}
+	for name := range keys {
+		if !found[name] {
+			return nil, fmt.Errorf("tfstate: %s.%s is not found in the state", resourceType, name)
+		}
+	}
+	if merged != nil {
+		merged["name"] = to
+		merged["instances"] = instances
+	}
+	v["resources"] = kept
+	return newState, nil
 }
 
-func (s *TFState) SetManageItemsAttr() (*TFState, error) {
-	q := setManageItemsQuery
-	return s.Query(q)
+// SetActivateAttr sets activate to true. If props are given, only the resources of the props are changed.
+func (s *TFState) SetActivateAttr(props ...TFBlockProp) (*TFState, error) {
+	return s.setAttr(props, func(r StateResource) string {
+		if activateResourceTypes[r.Type()] {
+			return "activate"
+		}
+		return ""
+	})
 }
 
-func (s *TFState) SetManageEntriesAttr() (*TFState, error) {
-	q := setManageEntriesQuery
-	return s.Query(q)
+// SetManageAttrs sets manage_* to true. If props are given, only the resources of the props are changed.
+func (s *TFState) SetManageAttrs(props ...TFBlockProp) (*TFState, error) {
+	return s.setAttr(props, func(r StateResource) string {
+		return manageAttrs[r.Type()]
+	})
 }
 
-func (s *TFState) SetManageAttrs() (*TFState, error) {
-	newState, err := s.SetManageEntriesAttr()
+// setAttr sets the attribute that attrOf returns for the resource to true
+func (s *TFState) setAttr(props []TFBlockProp, attrOf func(r StateResource) string) (*TFState, error) {
+	refs := make(map[string]bool, len(props))
+	for _, prop := range props {
+		refs[prop.GetRef()] = true
+	}
+
+	newState, err := s.Clone()
 	if err != nil {
 		return nil, err
 	}
-	newState, err = newState.SetManageItemsAttr()
+	resources, err := newState.Resources()
 	if err != nil {
 		return nil, err
 	}
-	return newState.SetManageSnippetsAttr()
+	for _, r := range resources {
+		if r.Mode() != "managed" || (len(refs) > 0 && !refs[r.Address()]) {
+			continue
+		}
+		attr := attrOf(r)
+		if attr == "" {
+			continue
+		}
+		for _, i := range r.Instances() {
+			i.Attributes().Set(attr, true)
+		}
+	}
+	return newState, nil
+}
+
+// ResourceAddresses returns the addresses of the managed resources in the state, e.g. fastly_service_vcl.service
+func (s *TFState) ResourceAddresses() []string {
+	resources, _ := s.Resources()
+	addrs := make([]string, 0, len(resources))
+	for _, r := range resources {
+		if r.Mode() == "managed" {
+			addrs = append(addrs, r.Address())
+		}
+	}
+	return addrs
 }
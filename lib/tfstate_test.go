@@ -0,0 +1,104 @@
+package terraformify
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// parseTestState returns the state in the JSON
+func parseTestState(t *testing.T, s string) *TFState {
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatal(err)
+	}
+	return &TFState{Value: v}
+}
+
+func TestMergeResources(t *testing.T) {
+	base := parseTestState(t, `{"serial": 1, "lineage": "abc", "resources": [{"mode": "managed", "type": "fastly_service_vcl", "name": "service"}]}`)
+	a := parseTestState(t, `{"serial": 1, "lineage": "x", "resources": [{"mode": "managed", "type": "fastly_service_dictionary_items", "name": "b"}]}`)
+	b := parseTestState(t, `{"serial": 1, "lineage": "y", "resources": [{"mode": "managed", "type": "fastly_service_acl_entries", "name": "a"}]}`)
+	c := parseTestState(t, `{"serial": 1, "lineage": "z", "resources": [{"mode": "managed", "type": "fastly_service_dictionary_items", "name": "a"}]}`)
+
+	// The order of the inputs must not affect the result
+	r1, err := base.MergeResources(a, b, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2, err := base.MergeResources(c, b, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r1.String() != r2.String() {
+		t.Errorf("merge result depends on the input order:\n%s\n%s", r1, r2)
+	}
+
+	expected := `{"lineage":"abc","resources":[` +
+		`{"mode":"managed","name":"a","type":"fastly_service_acl_entries"},` +
+		`{"mode":"managed","name":"a","type":"fastly_service_dictionary_items"},` +
+		`{"mode":"managed","name":"b","type":"fastly_service_dictionary_items"},` +
+		`{"mode":"managed","name":"service","type":"fastly_service_vcl"}],"serial":2}`
+	if r1.String() != expected {
+		t.Errorf("unexpected merge result:\n got: %s\nwant: %s", r1, expected)
+	}
+}
+
+func TestResourceNameByID(t *testing.T) {
+	var v interface{}
+	state := `{"resources": [
+		{"mode": "data", "type": "fastly_service_vcl", "name": "data", "instances": [{"attributes": {"id": "svc1"}}]},
+		{"mode": "managed", "type": "fastly_service_vcl", "name": "example", "instances": [{"attributes": {"id": "svc1"}}]}
+	]}`
+	if err := json.Unmarshal([]byte(state), &v); err != nil {
+		t.Fatal(err)
+	}
+	s := &TFState{Value: v}
+
+	if name, ok := s.ResourceNameByID("fastly_service_vcl", "svc1"); !ok || name != "example" {
+		t.Errorf("got %q, %v, want %q", name, ok, "example")
+	}
+	if _, ok := s.ResourceNameByID("fastly_service_vcl", "svc2"); ok {
+		t.Error("expected no resource for svc2")
+	}
+}
+
+func TestSetActivateAttrNarrowed(t *testing.T) {
+	var v interface{}
+	state := `{"resources": [
+		{"mode": "managed", "type": "fastly_service_vcl", "name": "existing", "instances": [{"attributes": {"activate": false}}]},
+		{"mode": "managed", "type": "fastly_service_vcl", "name": "imported", "instances": [{"attributes": {"activate": false}}]}
+	]}`
+	if err := json.Unmarshal([]byte(state), &v); err != nil {
+		t.Fatal(err)
+	}
+	serviceProp := NewVCLServiceResourceProp("svc", "imported", 0)
+	serviceProp.SetNormalizedName("imported")
+
+	s, err := (&TFState{Value: v}).SetActivateAttr(serviceProp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"resources":[` +
+		`{"instances":[{"attributes":{"activate":false}}],"mode":"managed","name":"existing","type":"fastly_service_vcl"},` +
+		`{"instances":[{"attributes":{"activate":true}}],"mode":"managed","name":"imported","type":"fastly_service_vcl"}]}`
+	if s.String() != expected {
+		t.Errorf("got %s, want %s", s, expected)
+	}
+}
+
+func TestSelectResources(t *testing.T) {
+	tfstate := parseTestState(t, `{"resources": [
+		{"mode": "managed", "type": "fastly_service_vcl", "name": "existing", "instances": [{"attributes": {"id": "a"}}]},
+		{"mode": "managed", "type": "fastly_service_vcl", "name": "imported", "instances": [{"attributes": {"id": "b"}}]}]}`)
+
+	selected, err := tfstate.SelectResources([]string{"fastly_service_vcl.imported"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := selected.ResourceAddresses(); len(got) != 1 || got[0] != "fastly_service_vcl.imported" {
+		t.Errorf("got %v", got)
+	}
+	if got := tfstate.ResourceAddresses(); len(got) != 2 {
+		t.Errorf("the original state is changed: %v", got)
+	}
+}
@@ -0,0 +1,369 @@
+package terraformify
+
+import (
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// DiscoverTLSResources looks up the TLS subscriptions, certificates, private keys, platform certificates
+// and activations bound to the domains, and returns them as props in the order they should be imported.
+func (c *FastlyClient) DiscoverTLSResources(domains []string, serviceProp *VCLServiceResourceProp) ([]TFBlockProp, error) {
+	domains = append([]string(nil), domains...)
+	sort.Strings(domains)
+
+	var subscriptions, certificates, platformCertificates, activations []TFBlockProp
+	// Names of the custom certificates keyed by the SHA-1 of their public keys
+	certKeys := make(map[string]string)
+	seen := make(map[string]bool)
+	add := func(list *[]TFBlockProp, prop TFBlockProp) {
+		key := prop.GetType() + "/" + prop.GetID()
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		*list = append(*list, prop)
+	}
+
+	for _, domain := range domains {
+		rs, err := c.listJSONAPI("/tls/subscriptions", url.Values{"filter[tls_domains.id]": {domain}})
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rs {
+			add(&subscriptions, NewTLSSubscriptionResourceProp(r.ID, domain, serviceProp))
+		}
+
+		rs, err = c.listJSONAPI("/tls/certificates", url.Values{"filter[tls_domains.id]": {domain}})
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rs {
+			name := r.stringAttribute("name")
+			if name == "" {
+				name = r.ID
+			}
+			add(&certificates, NewTLSCertificateResourceProp(r.ID, name, serviceProp))
+			sum, err := publicKeySHA1(r.stringAttribute("cert_blob"))
+			if err != nil {
+				log.Printf("[WARN] The private key of certificate %s is not imported as its public key is unknown: %v", name, err)
+				continue
+			}
+			certKeys[sum] = name
+		}
+
+		rs, err = c.listJSONAPI("/tls/bulk/certificates", url.Values{"filter[tls_domain.id]": {domain}})
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rs {
+			add(&platformCertificates, NewTLSPlatformCertificateResourceProp(r.ID, domain, serviceProp))
+		}
+
+		rs, err = c.listJSONAPI("/tls/activations", url.Values{"filter[tls_domain.id]": {domain}})
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rs {
+			add(&activations, NewTLSActivationResourceProp(r.ID, domain, serviceProp))
+		}
+	}
+
+	// Private keys have no relationship to domains or certificates in the API.
+	// Import the keys in use that have the public key of one of the certificates, leaving those of the other services.
+	var privateKeys []TFBlockProp
+	if len(certificates) > 0 {
+		rs, err := c.listJSONAPI("/tls/private_keys", url.Values{"filter[in_use]": {"true"}})
+		if err != nil {
+			return nil, err
+		}
+		matched := make(map[string]bool)
+		for _, r := range rs {
+			sum := strings.ToLower(r.stringAttribute("public_key_sha1"))
+			if _, ok := certKeys[sum]; !ok {
+				continue
+			}
+			matched[sum] = true
+			name := r.stringAttribute("name")
+			if name == "" {
+				name = r.ID
+			}
+			add(&privateKeys, NewTLSPrivateKeyResourceProp(r.ID, name, serviceProp))
+		}
+		unmatched := make([]string, 0)
+		for sum, name := range certKeys {
+			if !matched[sum] {
+				unmatched = append(unmatched, name)
+			}
+		}
+		sort.Strings(unmatched)
+		for _, name := range unmatched {
+			log.Printf("[WARN] No private key in use matches certificate %s. Import it with terraform import if it is managed", name)
+		}
+	}
+
+	props := make([]TFBlockProp, 0, len(subscriptions)+len(privateKeys)+len(certificates)+len(platformCertificates)+len(activations))
+	props = append(props, subscriptions...)
+	props = append(props, privateKeys...)
+	props = append(props, certificates...)
+	props = append(props, platformCertificates...)
+	props = append(props, activations...)
+	return props, nil
+}
+
+// publicKeySHA1 returns the SHA-1 of the public key of the PEM certificate, as in public_key_sha1 of the private keys
+func publicKeySHA1(certPEM string) (string, error) {
+	block, _ := pem.Decode([]byte(certPEM))
+	if block == nil {
+		return "", errors.New("no PEM certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return "", err
+	}
+	sum := sha1.Sum(cert.RawSubjectPublicKeyInfo)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// GetServiceDomains returns the names of the domain blocks of the service resource
+func (tfconf *TFConf) GetServiceDomains(serviceProp *VCLServiceResourceProp) ([]string, error) {
+	for _, block := range tfconf.Body().Blocks() {
+		labels := block.Labels()
+		if block.Type() != "resource" || len(labels) != 2 || labels[0] != serviceProp.GetType() {
+			continue
+		}
+
+		domains := make([]string, 0)
+		for _, nested := range block.Body().Blocks() {
+			if nested.Type() != "domain" {
+				continue
+			}
+			name, err := getStringAttributeValue(nested, "name")
+			if err != nil {
+				return nil, err
+			}
+			domains = append(domains, name)
+		}
+		return domains, nil
+	}
+	return nil, fmt.Errorf("tfconf: %s is not found", serviceProp.GetRef())
+}
+
+// RewriteTLSResources returns the configuration of the TLS resources in tfconf.
+// Other resources are dropped, read-only attributes are removed, and the domains and certificates are
+// replaced with references to the service and certificate resources.
+// Private keys are read from variables so that the key material is never written to the configuration.
+func (tfconf *TFConf) RewriteTLSResources(serviceProp *VCLServiceResourceProp, c Config) ([]byte, error) {
+	domains, err := tfconf.GetServiceDomains(serviceProp)
+	if err != nil {
+		return nil, err
+	}
+	serviceDomains := make(map[string]bool, len(domains))
+	for _, d := range domains {
+		serviceDomains[d] = true
+	}
+
+	tlsconf := tfconf.SelectResources(func(resourceType, name string) bool {
+		return strings.HasPrefix(resourceType, "fastly_tls_")
+	})
+
+	// Map certificate IDs to the expressions that refer to them
+	certRefs := make(map[string]hcl.Traversal)
+	for _, block := range tlsconf.Body().Blocks() {
+		labels := block.Labels()
+		switch labels[0] {
+		case "fastly_tls_certificate", "fastly_tls_platform_certificate":
+			id, err := getStringAttributeValue(block, "id")
+			if err != nil {
+				return nil, err
+			}
+			certRefs[id] = hcl.Traversal{
+				hcl.TraverseRoot{Name: labels[0]},
+				hcl.TraverseAttr{Name: labels[1]},
+				hcl.TraverseAttr{Name: "id"},
+			}
+		case "fastly_tls_subscription":
+			id, err := getStringAttributeValue(block, "certificate_id")
+			if errors.Is(err, ErrAttrNotFound) {
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+			certRefs[id] = hcl.Traversal{
+				hcl.TraverseRoot{Name: labels[0]},
+				hcl.TraverseAttr{Name: labels[1]},
+				hcl.TraverseAttr{Name: "certificate_id"},
+			}
+		}
+	}
+
+	variables := make([]string, 0)
+	for _, block := range tlsconf.Body().Blocks() {
+		body := block.Body()
+		name := block.Labels()[1]
+
+		switch block.Labels()[0] {
+		case "fastly_tls_subscription":
+			for _, key := range []string{"id", "certificate_id", "created_at", "updated_at", "state", "managed_dns_challenge", "managed_dns_challenges", "managed_http_challenges"} {
+				body.RemoveAttribute(key)
+			}
+
+			v, err := getAttributeValue(block, "domains")
+			if err != nil {
+				return nil, err
+			}
+			subDomains := make([]string, 0)
+			for it := v.ElementIterator(); it.Next(); {
+				_, d := it.Element()
+				subDomains = append(subDomains, d.AsString())
+			}
+			// Refer to the domains of the service only when all of them belong to it
+			if len(subDomains) > 0 && allIn(subDomains, serviceDomains) {
+				tokens, err := buildDomainsRef(serviceProp, subDomains)
+				if err != nil {
+					return nil, err
+				}
+				body.SetAttributeRaw("domains", tokens)
+			}
+		case "fastly_tls_certificate":
+			for _, key := range []string{"id", "created_at", "updated_at", "domains", "issued_to", "issuer", "replace", "serial_number", "signature_algorithm"} {
+				body.RemoveAttribute(key)
+			}
+			err := replaceWithPEMFile(block, "certificate_body", fmt.Sprintf("%s.pem", name), c)
+			if err != nil {
+				return nil, err
+			}
+		case "fastly_tls_platform_certificate":
+			for _, key := range []string{"id", "created_at", "updated_at", "domains", "not_after", "not_before", "replace"} {
+				body.RemoveAttribute(key)
+			}
+			err := replaceWithPEMFile(block, "certificate_body", fmt.Sprintf("%s.pem", name), c)
+			if err != nil {
+				return nil, err
+			}
+			err = replaceWithPEMFile(block, "intermediates_blob", fmt.Sprintf("%s_intermediates.pem", name), c)
+			if err != nil {
+				return nil, err
+			}
+		case "fastly_tls_private_key":
+			for _, key := range []string{"id", "created_at", "key_length", "key_type", "public_key_sha1", "replace"} {
+				body.RemoveAttribute(key)
+			}
+
+			// The API never returns the key, so read it from a sensitive variable, which is empty by default,
+			// and ignore the difference from the empty value in the state.
+			variable := fmt.Sprintf("tls_private_key_%s", name)
+			variables = append(variables, variable)
+			body.SetAttributeTraversal("key_pem", hcl.Traversal{
+				hcl.TraverseRoot{Name: "var"},
+				hcl.TraverseAttr{Name: variable},
+			})
+			lifecycle := body.AppendNewBlock("lifecycle", nil)
+			tokens, err := buildExpression("[key_pem]")
+			if err != nil {
+				return nil, err
+			}
+			lifecycle.Body().SetAttributeRaw("ignore_changes", tokens)
+		case "fastly_tls_activation":
+			body.RemoveAttribute("id")
+			body.RemoveAttribute("created_at")
+
+			domain, err := getStringAttributeValue(block, "domain")
+			if err != nil {
+				return nil, err
+			}
+			if serviceDomains[domain] {
+				tokens, err := buildDomainRef(serviceProp, domain)
+				if err != nil {
+					return nil, err
+				}
+				body.SetAttributeRaw("domain", tokens)
+			}
+
+			certID, err := getStringAttributeValue(block, "certificate_id")
+			if err != nil {
+				return nil, err
+			}
+			if ref, ok := certRefs[certID]; ok {
+				body.SetAttributeTraversal("certificate_id", ref)
+			}
+		}
+	}
+
+	body := tlsconf.Body()
+	for _, variable := range variables {
+		body.AppendNewline()
+		block := body.AppendNewBlock("variable", []string{variable})
+		block.Body().SetAttributeTraversal("type", hcl.Traversal{hcl.TraverseRoot{Name: "string"}})
+		block.Body().SetAttributeValue("sensitive", cty.True)
+		// terraform refresh and plan run without input, and the key is ignored once imported
+		block.Body().SetAttributeValue("default", cty.StringVal(""))
+	}
+
+	return hclwrite.Format(tlsconf.Bytes()), nil
+}
+
+// replaceWithPEMFile writes the PEM in the attribute to a file and replaces the attribute with the file function.
+// If the state does not have the PEM, the file function is set anyway so that the user can place the file.
+func replaceWithPEMFile(block *hclwrite.Block, attrKey, filename string, c Config) error {
+	body := block.Body()
+	path := c.filePath("tls", filename)
+
+	v, err := getAttributeValue(block, attrKey)
+	if err != nil && !errors.Is(err, ErrAttrNotFound) {
+		return err
+	}
+	if err == nil && !v.IsNull() && v.Type() == cty.String && v.AsString() != "" {
+		if err := saveTLSFile(c.fileDir(), filename, []byte(v.AsString())); err != nil {
+			return err
+		}
+	} else {
+		log.Printf("[WARN] %s of %s is not available from the API. Place the PEM at %s", attrKey, block.Labels()[1], filepath.Join(c.Directory, filepath.FromSlash(path)))
+	}
+
+	body.SetAttributeRaw(attrKey, buildFileFunction(path))
+	return nil
+}
+
+func saveTLSFile(workingDir, name string, content []byte) error {
+	return saveFile(workingDir, path.Join("tls", name), content)
+}
+
+// buildDomainRef builds an expression that picks the domain from the domain blocks of the service
+func buildDomainRef(serviceProp *VCLServiceResourceProp, domain string) (hclwrite.Tokens, error) {
+	return buildExpression(fmt.Sprintf("one([for d in %s.domain : d.name if d.name == %s])",
+		serviceProp.GetRef(), strconv.Quote(domain)))
+}
+
+// buildDomainsRef builds an expression that picks the domains from the domain blocks of the service
+func buildDomainsRef(serviceProp *VCLServiceResourceProp, domains []string) (hclwrite.Tokens, error) {
+	quoted := make([]string, 0, len(domains))
+	for _, d := range domains {
+		quoted = append(quoted, strconv.Quote(d))
+	}
+	return buildExpression(fmt.Sprintf("[for d in %s.domain : d.name if contains([%s], d.name)]",
+		serviceProp.GetRef(), strings.Join(quoted, ", ")))
+}
+
+func allIn(values []string, set map[string]bool) bool {
+	for _, v := range values {
+		if !set[v] {
+			return false
+		}
+	}
+	return true
+}
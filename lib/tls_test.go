@@ -0,0 +1,239 @@
+package terraformify
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+const tlsRawHCL = `# fastly_service_vcl.service:
+resource "fastly_service_vcl" "service" {
+    id   = "svc"
+    name = "example"
+
+    domain {
+        name = "www.example.com"
+    }
+}
+
+# fastly_tls_activation.www_example_com:
+resource "fastly_tls_activation" "www_example_com" {
+    certificate_id   = "cert1"
+    configuration_id = "conf1"
+    created_at       = "2022-01-01T00:00:00Z"
+    domain           = "www.example.com"
+    id               = "act1"
+}
+
+# fastly_tls_certificate.my_cert:
+resource "fastly_tls_certificate" "my_cert" {
+    certificate_body    = <<-EOT
+        -----BEGIN CERTIFICATE-----
+        MIIB
+        -----END CERTIFICATE-----
+    EOT
+    created_at          = "2022-01-01T00:00:00Z"
+    domains             = [
+        "www.example.com",
+    ]
+    id                  = "cert1"
+    issued_to           = "www.example.com"
+    issuer              = "Example CA"
+    name                = "my-cert"
+    replace             = false
+    serial_number       = "1"
+    signature_algorithm = "SHA256-RSA"
+    updated_at          = "2022-01-01T00:00:00Z"
+}
+
+# fastly_tls_private_key.my_key:
+resource "fastly_tls_private_key" "my_key" {
+    created_at      = "2022-01-01T00:00:00Z"
+    id              = "key1"
+    key_length      = 2048
+    key_pem         = (sensitive value)
+    key_type        = "RSA"
+    name            = "my-key"
+    public_key_sha1 = "abc"
+    replace         = false
+}
+`
+
+const tlsGolden = `# fastly_tls_activation.www_example_com:
+resource "fastly_tls_activation" "www_example_com" {
+  certificate_id   = fastly_tls_certificate.my_cert.id
+  configuration_id = "conf1"
+  domain           = one([for d in fastly_service_vcl.service.domain : d.name if d.name == "www.example.com"])
+}
+
+# fastly_tls_certificate.my_cert:
+resource "fastly_tls_certificate" "my_cert" {
+  certificate_body = file("${path.module}/tls/my_cert.pem")
+  name             = "my-cert"
+}
+
+# fastly_tls_private_key.my_key:
+resource "fastly_tls_private_key" "my_key" {
+  key_pem = var.tls_private_key_my_key
+  name    = "my-key"
+  lifecycle {
+    ignore_changes = [key_pem]
+  }
+}
+
+variable "tls_private_key_my_key" {
+  type      = string
+  sensitive = true
+  default   = ""
+}
+`
+
+func TestRewriteTLSResources(t *testing.T) {
+	dir := t.TempDir()
+	serviceProp := NewVCLServiceResourceProp("svc", "service", 0)
+
+	tfconf, err := LoadTFConf(tlsRawHCL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := tfconf.RewriteTLSResources(serviceProp, Config{Directory: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(result) != tlsGolden {
+		t.Errorf("unexpected result:\n%s", result)
+	}
+	if formatted := hclwrite.Format(result); string(formatted) != string(result) {
+		t.Errorf("the result is not formatted:\n%s", result)
+	}
+
+	pem, err := os.ReadFile(dir + "/tls/my_cert.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(pem) != "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n" {
+		t.Errorf("unexpected certificate file:\n%s", pem)
+	}
+}
+
+// newTestCertificate returns a self-signed PEM certificate and the SHA-1 of its public key
+func newTestCertificate(t *testing.T, domain string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: domain},
+		DNSNames:     []string{domain},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha1.Sum(cert.RawSubjectPublicKeyInfo)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})), hex.EncodeToString(sum[:])
+}
+
+func TestDiscoverTLSPrivateKeys(t *testing.T) {
+	certPEM, sum := newTestCertificate(t, "www.example.com")
+	_, otherSum := newTestCertificate(t, "other.example.com")
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var data []jsonAPIResource
+		switch r.URL.Path {
+		case "/tls/certificates":
+			data = []jsonAPIResource{{ID: "cert1", Attributes: map[string]interface{}{"name": "my-cert", "cert_blob": certPEM}}}
+		case "/tls/private_keys":
+			data = []jsonAPIResource{
+				{ID: "key1", Attributes: map[string]interface{}{"name": "my-key", "public_key_sha1": sum}},
+				// The key of the certificate of another service
+				{ID: "key2", Attributes: map[string]interface{}{"name": "other-key", "public_key_sha1": otherSum}},
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+	defer ts.Close()
+
+	client := NewFastlyClient("key")
+	client.Endpoint = ts.URL
+	props, err := client.DiscoverTLSResources([]string{"www.example.com"}, NewVCLServiceResourceProp("svc", "service", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"fastly_tls_private_key.my-key", "fastly_tls_certificate.my-cert"}
+	if len(props) != len(want) {
+		t.Fatalf("got %d props, want %d", len(props), len(want))
+	}
+	for i, prop := range props {
+		if prop.GetRef() != want[i] {
+			t.Errorf("props[%d] = %s, want %s", i, prop.GetRef(), want[i])
+		}
+	}
+}
+
+// The imported private key is refreshed and planned without input, as terraform does from importService and drift
+func TestRewriteTLSResourcesWithoutInput(t *testing.T) {
+	tfconf, err := LoadTFConf(tlsRawHCL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := tfconf.RewriteTLSResources(NewVCLServiceResourceProp("svc", "service", 0), Config{Directory: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, diags := hclsyntax.ParseConfig(result, "tls.tf", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+
+	vars := make(map[string]cty.Value)
+	var keyPEM hclsyntax.Expression
+	for _, block := range f.Body.(*hclsyntax.Body).Blocks {
+		switch {
+		case block.Type == "variable":
+			attr, ok := block.Body.Attributes["default"]
+			if !ok {
+				t.Fatalf("variable %s has no default and requires input", block.Labels[0])
+			}
+			v, diags := attr.Expr.Value(nil)
+			if diags.HasErrors() {
+				t.Fatal(diags)
+			}
+			vars[block.Labels[0]] = v
+		case block.Type == "resource" && block.Labels[0] == "fastly_tls_private_key":
+			keyPEM = block.Body.Attributes["key_pem"].Expr
+		}
+	}
+	if keyPEM == nil {
+		t.Fatalf("no private key in:\n%s", result)
+	}
+	if _, diags := keyPEM.Value(&hcl.EvalContext{Variables: map[string]cty.Value{"var": cty.ObjectVal(vars)}}); diags.HasErrors() {
+		t.Error(diags)
+	}
+}
@@ -0,0 +1,196 @@
+package terraformify
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// unifiedContext is the number of unchanged lines shown around the changes
+const unifiedContext = 3
+
+// maxEditDistance bounds the memory used to diff files that have little in common
+const maxEditDistance = 2048
+
+type lineOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+}
+
+// UnifiedDiff returns the unified diff of the two texts, or nil if they are identical
+func UnifiedDiff(fromName, toName string, from, to []byte) []byte {
+	if bytes.Equal(from, to) {
+		return nil
+	}
+	ops := diffLines(splitLines(from), splitLines(to))
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "--- %s\n+++ %s\n", fromName, toName)
+
+	for start := 0; start < len(ops); {
+		// Find the next change
+		for start < len(ops) && ops[start].kind == ' ' {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+
+		// Extend the hunk while the changes are close enough to share the context
+		hunkStart := start - unifiedContext
+		if hunkStart < 0 {
+			hunkStart = 0
+		}
+		end := start
+		for i := start; i < len(ops); i++ {
+			if ops[i].kind != ' ' {
+				end = i + 1
+			} else if i-end >= 2*unifiedContext {
+				break
+			}
+		}
+		hunkEnd := end + unifiedContext
+		if hunkEnd > len(ops) {
+			hunkEnd = len(ops)
+		}
+
+		// Line numbers of the hunk in each text
+		fromLine, toLine := 1, 1
+		for _, op := range ops[:hunkStart] {
+			if op.kind != '+' {
+				fromLine++
+			}
+			if op.kind != '-' {
+				toLine++
+			}
+		}
+		fromCount, toCount := 0, 0
+		for _, op := range ops[hunkStart:hunkEnd] {
+			if op.kind != '+' {
+				fromCount++
+			}
+			if op.kind != '-' {
+				toCount++
+			}
+		}
+		fmt.Fprintf(&buf, "@@ -%s +%s @@\n", hunkRange(fromLine, fromCount), hunkRange(toLine, toCount))
+		for _, op := range ops[hunkStart:hunkEnd] {
+			buf.WriteByte(op.kind)
+			buf.WriteString(op.line)
+			buf.WriteByte('\n')
+		}
+
+		start = hunkEnd
+	}
+	return buf.Bytes()
+}
+
+func hunkRange(line, count int) string {
+	if count == 0 {
+		// An empty range refers to the line before it
+		return fmt.Sprintf("%d,0", line-1)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", line)
+	}
+	return fmt.Sprintf("%d,%d", line, count)
+}
+
+func splitLines(b []byte) []string {
+	s := strings.TrimSuffix(string(b), "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+// diffLines computes the shortest edit script between the lines with the Myers algorithm
+func diffLines(a, b []string) []lineOp {
+	n, m := len(a), len(b)
+	maxD := n + m
+	offset := maxD + 1
+	v := make([]int, 2*maxD+3)
+	trace := make([][]int, 0)
+
+	for d := 0; d <= maxD; d++ {
+		if d > maxEditDistance {
+			return replaceLines(a, b)
+		}
+		// Only the diagonals from -d-1 to d+1 are needed to backtrack
+		snapshot := make([]int, 2*d+3)
+		copy(snapshot, v[offset-d-1:offset+d+2])
+		trace = append(trace, snapshot)
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, a, b)
+			}
+		}
+	}
+	return nil
+}
+
+func backtrack(trace [][]int, a, b []string) []lineOp {
+	x, y := len(a), len(b)
+	ops := make([]lineOp, 0, x+y)
+	for d := len(trace) - 1; d >= 0; d-- {
+		// trace[d] holds the diagonals from -d-1
+		v := trace[d]
+		offset := d + 1
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, lineOp{kind: ' ', line: a[x]})
+		}
+		if d > 0 {
+			if x == prevX {
+				y--
+				ops = append(ops, lineOp{kind: '+', line: b[y]})
+			} else {
+				x--
+				ops = append(ops, lineOp{kind: '-', line: a[x]})
+			}
+		}
+	}
+
+	// Reverse the operations collected from the end
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// replaceLines returns the edit script that removes all the lines and adds the new ones
+func replaceLines(a, b []string) []lineOp {
+	ops := make([]lineOp, 0, len(a)+len(b))
+	for _, line := range a {
+		ops = append(ops, lineOp{kind: '-', line: line})
+	}
+	for _, line := range b {
+		ops = append(ops, lineOp{kind: '+', line: line})
+	}
+	return ops
+}
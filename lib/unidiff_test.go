@@ -0,0 +1,32 @@
+package terraformify
+
+import "testing"
+
+func TestUnifiedDiff(t *testing.T) {
+	from := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\n"
+	to := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\n"
+
+	want := `--- a/main.vcl
++++ b/main.vcl
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -10,3 +10,4 @@
+ j
+ k
+ l
++m
+`
+	got := UnifiedDiff("a/main.vcl", "b/main.vcl", []byte(from), []byte(to))
+	if string(got) != want {
+		t.Errorf("unexpected diff:\n%s", got)
+	}
+
+	if got := UnifiedDiff("a", "b", []byte(from), []byte(from)); got != nil {
+		t.Errorf("expected no diff, got:\n%s", got)
+	}
+}
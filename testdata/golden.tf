@@ -67,7 +67,7 @@ resource "fastly_service_dictionary_items" "redirect_table" {
 
 # fastly_service_dynamic_snippet_content.my_dynamic_snippet_one:
 resource "fastly_service_dynamic_snippet_content" "my_dynamic_snippet_one" {
-  content    = file("./vcl/dsnippet_my_dynamic_snippet_one.vcl")
+  content    = file("${path.module}/vcl/dsnippet_my_dynamic_snippet_one.vcl")
   service_id = fastly_service_vcl.service.id
   snippet_id = each.value.snippet_id
   for_each = {
@@ -77,7 +77,7 @@ resource "fastly_service_dynamic_snippet_content" "my_dynamic_snippet_one" {
 
 # fastly_service_dynamic_snippet_content.my_dynamic_snippet_two:
 resource "fastly_service_dynamic_snippet_content" "my_dynamic_snippet_two" {
-  content    = file("./vcl/dsnippet_my_dynamic_snippet_two.vcl")
+  content    = file("${path.module}/vcl/dsnippet_my_dynamic_snippet_two.vcl")
   service_id = fastly_service_vcl.service.id
   snippet_id = each.value.snippet_id
   for_each = {
@@ -337,7 +337,7 @@ resource "fastly_service_vcl" "service" {
 
   logging_papertrail {
     address            = "xxx.papertrail.com"
-    format             = file("./logformat/weblogs.json")
+    format             = file("${path.module}/logformat/weblogs.json")
     format_version     = 2
     name               = "weblogs"
     port               = 12345
@@ -345,7 +345,7 @@ resource "fastly_service_vcl" "service" {
   }
   logging_papertrail {
     address        = "xxx.papertrail.com"
-    format         = file("./logformat/waflogs.json")
+    format         = file("${path.module}/logformat/waflogs.json")
     format_version = 2
     name           = "waflogs"
     placement      = "waf_debug"
@@ -355,7 +355,7 @@ resource "fastly_service_vcl" "service" {
   logging_s3 {
     bucket_name      = "my_s3_bucket"
     domain           = "s3.amazonaws.com"
-    format           = file("./logformat/my_s3_endpoint.txt")
+    format           = file("${path.module}/logformat/my_s3_endpoint.txt")
     format_version   = 2
     gzip_level       = 0
     message_type     = "blank"
@@ -385,10 +385,10 @@ resource "fastly_service_vcl" "service" {
     request_condition = "Generated by IP block list"
     response          = "Forbidden"
     status            = 403
-    content           = file("./content/generated_by_ip_block_list.txt")
+    content           = file("${path.module}/content/generated_by_ip_block_list.html")
   }
   response_object {
-    content           = file("./content/generated_by_synthetic_response_for_robots_txt.txt")
+    content           = file("${path.module}/content/generated_by_synthetic_response_for_robots_txt.txt")
     content_type      = "text/plain"
     name              = "Generated by synthetic response for robots.txt"
     request_condition = "Generated by synthetic response for robots.txt"
@@ -396,7 +396,7 @@ resource "fastly_service_vcl" "service" {
     status            = 200
   }
   response_object {
-    content           = file("./content/waf_response.txt")
+    content           = file("${path.module}/content/waf_response.json")
     content_type      = "application/json"
     name              = "WAF_Response"
     request_condition = "false"
@@ -405,7 +405,7 @@ resource "fastly_service_vcl" "service" {
   }
   response_object {
     cache_condition = "Generated by synthetic response for 404 page"
-    content         = file("./content/generated_by_synthetic_response_for_404_page.txt")
+    content         = file("${path.module}/content/generated_by_synthetic_response_for_404_page.html")
     content_type    = "text/html"
     name            = "Generated by synthetic response for 404 page"
     response        = "Not Found"
@@ -413,7 +413,7 @@ resource "fastly_service_vcl" "service" {
   }
   response_object {
     cache_condition = "Generated by synthetic response for 503 page"
-    content         = file("./content/generated_by_synthetic_response_for_503_page.txt")
+    content         = file("${path.module}/content/generated_by_synthetic_response_for_503_page.html")
     content_type    = "text/html"
     name            = "Generated by synthetic response for 503 page"
     response        = "Service Unavailable"
@@ -421,43 +421,43 @@ resource "fastly_service_vcl" "service" {
   }
 
   snippet {
-    content  = file("./vcl/snippet_fastly_csi_init.vcl")
+    content  = file("${path.module}/vcl/snippet_fastly_csi_init.vcl")
     name     = "fastly_csi_init"
     priority = 5
     type     = "recv"
   }
   snippet {
-    content  = file("./vcl/snippet_error_redirects.vcl")
+    content  = file("${path.module}/vcl/snippet_error_redirects.vcl")
     name     = "error_redirects"
     priority = 100
     type     = "error"
   }
   snippet {
-    content  = file("./vcl/snippet_recv_redirects.vcl")
+    content  = file("${path.module}/vcl/snippet_recv_redirects.vcl")
     name     = "recv_redirects"
     priority = 100
     type     = "recv"
   }
   snippet {
-    content  = file("./vcl/snippet_recv_allow_list.vcl")
+    content  = file("${path.module}/vcl/snippet_recv_allow_list.vcl")
     name     = "recv_allow_list"
     priority = 90
     type     = "recv"
   }
   snippet {
-    content  = file("./vcl/snippet_fastly_waf_snippet.vcl")
+    content  = file("${path.module}/vcl/snippet_fastly_waf_snippet.vcl")
     name     = "Fastly_WAF_Snippet"
     priority = 10
     type     = "recv"
   }
 
   vcl {
-    content = file("./vcl/main.vcl")
+    content = file("${path.module}/vcl/main.vcl")
     main    = true
     name    = "main"
   }
   vcl {
-    content = file("./vcl/config_check.vcl")
+    content = file("${path.module}/vcl/config_check.vcl")
     main    = false
     name    = "config_check"
   }